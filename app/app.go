@@ -9,118 +9,189 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/keys"
 	"github.com/glekoz/online-shop_user/mail"
+	"github.com/glekoz/online-shop_user/metrics"
 	"github.com/glekoz/online-shop_user/repository"
 	"github.com/glekoz/online-shop_user/shared/logger"
 	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type RepoAPI interface {
-	CreateUser(ctx context.Context, id, name, email, hashedPassword string) error
-	PromoteModer(ctx context.Context, id string) error
-	PromoteAdmin(ctx context.Context, id string) error
-	PromoteCoreAdmin(ctx context.Context, id string) error
+	CreateUser(ctx context.Context, id, name, email, hashedPassword, locale string, token models.MailToken, confirmation models.OutboxMail) error
 	GetUserByID(ctx context.Context, id string) (models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (models.UserTokenWithPassword, error)
 	GetUsersByEmail(ctx context.Context, email string) ([]models.UserInfo, error)
-	GetModer(ctx context.Context, id string) (string, error)
-	GetAdmin(ctx context.Context, id string) (models.Admin, error)
+	GetPermissions(ctx context.Context, ids []string) ([]models.Permissions, error)
 	ConfirmEmail(ctx context.Context, id string) error
-	ChangeName(ctx context.Context, id, newName string) error
+	GetUserTokenByID(ctx context.Context, id string) (models.UserToken, error)
+	UpdateProfile(ctx context.Context, id string, upd models.ProfileUpdate) error
 	ChangePassword(ctx context.Context, id, newHashedPassword string) error
-	ChangeEmail(ctx context.Context, id, newEmail string) error
-	DeleteUser(ctx context.Context, id string) error
-	DeleteModer(ctx context.Context, id string) error
-	DeleteAdmin(ctx context.Context, id string) error
+	GetPasswordHistory(ctx context.Context, id string, limit int) ([]string, error)
+	SetPendingEmail(ctx context.Context, id, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, id, newEmail string) error
+	SetBanned(ctx context.Context, id string, banned bool) error
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	CancelDeletion(ctx context.Context, id string) (bool, error)
+	GetDueDeletions(ctx context.Context, limit int) ([]string, error)
+	AnonymizeUser(ctx context.Context, id string) error
+	AddAuditEntry(ctx context.Context, action, targetID string, before, after any) error
+	GetAuditLog(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error)
+
+	GetLoginFailures(ctx context.Context, email, ip string) ([]models.LoginFailures, error)
+	ClaimLoginAttempt(ctx context.Context, email, ip string, p models.LoginPolicy) (models.LoginAttempt, error)
+	ResetLoginFailures(ctx context.Context, email, ip string) error
+	DeleteStaleLoginFailures(ctx context.Context, olderThan time.Time) (int64, error)
+
+	ClaimMails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error)
+	ReleaseMails(ctx context.Context, ids []int64) error
+	MarkMailSent(ctx context.Context, id int64, messageID string) error
+	MarkMailFailed(ctx context.Context, id int64, next time.Time, reason string) error
+	MarkMailDead(ctx context.Context, id int64, reason string) error
+	GetOutboxStats(ctx context.Context) (models.OutboxStats, error)
+	DeleteSentMails(ctx context.Context, olderThan time.Time) (int64, error)
+	DeleteExpiredMailTokens(ctx context.Context) (int64, error)
+
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+	GrantRole(ctx context.Context, userID, role, actorID string) error
+	RevokeRole(ctx context.Context, userID, role, actorID string) error
+
+	CreateRefreshToken(ctx context.Context, hash, userID, familyID string, expiresAt time.Time) error
+	GetRefreshToken(ctx context.Context, hash string) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
 }
 
+// токены из писем хранятся в БД, ошибка проверки - сбой БД, а не неверный токен
 type MailAPI interface {
-	SendEmailConfirmationMessage(userID, email string, mailtoken, link string) (string, error)
-	CheckToken(userID, token string) bool
+	EmailConfirmationMail(locale, userID, email string, mailtoken, link string) (models.MailToken, models.OutboxMail, error)
+	Deliver(ctx context.Context, m models.OutboxMail) (string, error)
+	SendEmailConfirmationMessage(locale, userID, email string, mailtoken, link string) (string, error)
+	CheckToken(userID, token string) (bool, error)
+	SendPasswordResetMessage(locale, userID, email string, resettoken, link string) (string, error)
+	ValidResetToken(userID, token string) (bool, error)
+	DeleteResetToken(userID string) error
+	SendPasswordChangedMessage(locale, email string) (string, error)
+	SendEmailChangeMessage(locale, userID, newEmail string, mailtoken, link string) (string, error)
+	CheckEmailChangeToken(userID, newEmail, token string) (bool, error)
+	SendEmailChangeNotice(locale, oldEmail, newEmail string) (string, error)
+	SendAccountDeletionMessage(locale, email string, at time.Time) (string, error)
+	SendAccountUnlockMessage(locale, userID, email string, unlocktoken, link string) (string, error)
+	CheckUnlockToken(userID, token string) (bool, error)
 }
-type CacheAPI interface {
-	Add(userID, token string) error
-	Get(userID string) (string, bool)
-	Delete(userID string)
+
+// хранит отозванные аксесс токены, пока они не истекут
+type DenylistAPI interface {
+	AddWithTTL(key, value string, ttl time.Duration) error
+	Get(key string) (string, bool)
 }
 
-type App struct {
-	Repo RepoAPI
-	Mail MailAPI
-	// MailTable CacheAPI
-	logger *slog.Logger
+// счетчики для мониторинга, см. metrics.Metrics
+type MetricsAPI interface {
+	Registered()
+	LoginAttempt(result string)
+	TokensIssued(reason string)
+	MailDelivery(result string)
+}
+
+// ключи подписи токенов, подписывает всегда самый новый
+type KeysAPI interface {
+	SigningKey() (kid string, key *rsa.PrivateKey)
+	PublicKey(kid string) (*rsa.PublicKey, bool)
+	PublicKeys() []keys.PublicKey
+}
 
-	frontAddr  string
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+type App struct {
+	Repo     RepoAPI
+	Mail     MailAPI
+	denylist DenylistAPI
+	logger   *slog.Logger
+	metrics  MetricsAPI
+
+	frontAddr     string
+	keys          KeysAPI
+	deletionGrace time.Duration // через сколько удаляется аккаунт после запроса
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+	bcryptCost    int
 }
 
-func New(repo RepoAPI, mail MailAPI, mt CacheAPI, log *slog.Logger, frontAddr string, privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) *App {
+func New(repo RepoAPI, mail MailAPI, denylist DenylistAPI, log *slog.Logger, keys KeysAPI, m MetricsAPI, cfg config.App) *App {
 	return &App{
 		Repo: repo,
 		Mail: mail,
 		// MailTable: mt,
-		logger: log,
+		denylist: denylist,
+		logger:   log,
+		metrics:  m,
 
-		frontAddr:  frontAddr,
-		privateKey: privateKey,
-		publicKey:  publicKey,
+		frontAddr:     cfg.FrontAddr,
+		keys:          keys,
+		deletionGrace: cfg.DeletionGrace,
+		accessTTL:     cfg.AccessTokenTTL,
+		refreshTTL:    cfg.RefreshTokenTTL,
+		bcryptCost:    cfg.BcryptCost,
 	}
 }
 
 // для токена возвращается айди и имя, а остальное - false
 // не возвращается, а используется
-func (a *App) Register(ctx context.Context, name, email, barePassword string) (access string, refresh string, err error) {
+// пустой locale - models.DefaultLocale
+func (a *App) Register(ctx context.Context, name, email, barePassword, locale string) (access string, refresh string, err error) {
+	ctx, span := tracing.Start(ctx, "App.Register")
+	defer span.End()
+	if locale == "" {
+		locale = models.DefaultLocale
+	}
 	id, err := uuid.NewV7()
 	if err != nil {
 		return "", "", err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(barePassword), bcrypt.DefaultCost)
+	hashedPassword, err := a.hashPassword(ctx, barePassword)
 	if err != nil {
 		return "", "", err
 	}
 
-	err = a.Repo.CreateUser(ctx, id.String(), name, email, string(hashedPassword))
+	// письмо уходит в очередь вместе с пользователем, поэтому регистрация
+	// не ждет почтового провайдера
+	mailtoken := rand.Text()
+	link := fmt.Sprintf("%s/confirm/%s/%s", a.frontAddr, id.String(), mailtoken)
+	token, confirmation, err := a.Mail.EmailConfirmationMail(locale, id.String(), email, mailtoken, link)
 	if err != nil {
-		if errors.Is(err, repository.ErrAlreadyExists) {
-			return "", "", ErrUserAlreadyExists
-		}
 		return "", "", err
 	}
-
-	access, err = a.CreateJWTToken(id.String(), name, false, false, false)
+	err = a.Repo.CreateUser(ctx, id.String(), name, email, string(hashedPassword), locale, token, confirmation)
 	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return "", "", ErrUserAlreadyExists
+		}
 		return "", "", err
 	}
+	a.logger.InfoContext(ctx, "email queued", "data", map[string]string{"email": email})
+	a.metrics.Registered()
 
-	refresh, err = a.CreateRefreshToken(id.String(), name, false, false, false)
+	access, refresh, err = a.startSession(ctx, models.UserToken{ID: id.String(), Name: name}, metrics.TokensRegister)
 	if err != nil {
 		return "", "", err
 	}
-
-	msgID, err := a.sendEmailConfirmation(id.String(), email)
-	if err != nil {
-		if errors.Is(err, mail.ErrMsgAlreadySent) {
-			a.logger.InfoContext(ctx, "email message has already been sent", "input data", map[string]string{"email": email})
-		} else {
-			a.logger.ErrorContext(ctx, "mail malfunction", "data", map[string]string{"email": email})
-		}
-	}
-	a.logger.InfoContext(ctx, "email sent", "data", map[string]string{"email": email, "msgID": msgID})
-
 	return access, refresh, nil
 }
 
 // мб стоит в горутине это отправлять
 // если в горутине, то ошибку можно и игнорировать
-func (a *App) sendEmailConfirmation(userID, email string) (string, error) {
+func (a *App) sendEmailConfirmation(locale, userID, email string) (string, error) {
 	mailtoken := rand.Text()
 	link := fmt.Sprintf("%s/confirm/%s/%s", a.frontAddr, userID, mailtoken)
-	msgID, err := a.Mail.SendEmailConfirmationMessage(userID, email, mailtoken, link)
+	msgID, err := a.Mail.SendEmailConfirmationMessage(locale, userID, email, mailtoken, link)
 	if err != nil {
 		return "", err
 	}
@@ -129,6 +200,8 @@ func (a *App) sendEmailConfirmation(userID, email string) (string, error) {
 
 // этот запрос поступает из личного кабинета, поэтому необходимо сверить айди отправителя и айди запрашиваемого аккаунта
 func (a *App) RequestEmailConfirmation(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.RequestEmailConfirmation")
+	defer span.End()
 	RUID, err := getRUID(ctx)
 	if err != nil {
 		return ErrNoRUID
@@ -150,19 +223,19 @@ func (a *App) RequestEmailConfirmation(ctx context.Context, userID string) error
 		return logger.WrapError(ctx, ErrEmailAlreadyConfirmed)
 	}
 
-	msgID, err := a.sendEmailConfirmation(userID, user.Email)
+	msgID, err := a.sendEmailConfirmation(user.Locale, userID, user.Email)
 	if err != nil {
 		if errors.Is(err, mail.ErrMsgAlreadySent) {
 			return logger.WrapError(ctx, ErrMsgAlreadySent)
 		}
 		return logger.WrapError(ctx, err)
 	}
-	a.logger.InfoContext(ctx, "email sent", "msgID", msgID)
+	a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
 
-	// генерация токена и сохранение его в кэше вместе с userID
+	// генерация токена и сохранение его хэша в БД вместе с userID
 	//
 	// отправка письма с ссылкой на подтверждение почты
-	// ссылка типа /confirm/<uid>/<token>, где токен хранится в кэше в паре userID : token
+	// ссылка типа /confirm/<uid>/<token>, где хэш токена хранится в БД под ключом userID
 	// и при переходе по ссылке посмотреть, валиден ли токен для этого пользователя
 	// и если да, то подтвердить почту
 	//
@@ -171,13 +244,18 @@ func (a *App) RequestEmailConfirmation(ctx context.Context, userID string) error
 
 // ссылка на этот метод будет в самом письме - тут тоже надо проверять таблицу
 func (a *App) ConfirmEmail(ctx context.Context, userID, mailtoken string) error {
-	ok := a.Mail.CheckToken(userID, mailtoken)
+	ctx, span := tracing.Start(ctx, "App.ConfirmEmail")
+	defer span.End()
+	ok, err := a.Mail.CheckToken(userID, mailtoken)
+	if err != nil {
+		return logger.WrapError(logger.WithDetails(ctx, "id", userID), err)
+	}
 	if !ok {
 		ctx = logger.WithDetails(ctx, "mail token", mailtoken)
 		return logger.WrapError(ctx, ErrWrongMailToken)
 	}
 
-	err := a.Repo.ConfirmEmail(ctx, userID)
+	err = a.Repo.ConfirmEmail(ctx, userID)
 	if err != nil {
 		ctx = logger.WithDetails(ctx, "id", userID)
 		if errors.Is(err, repository.ErrNotFound) {
@@ -188,144 +266,359 @@ func (a *App) ConfirmEmail(ctx context.Context, userID, mailtoken string) error
 	return nil
 }
 
+// новое имя попадет в токены при следующем обновлении по рефреш токену
+func (a *App) UpdateProfile(ctx context.Context, upd models.ProfileUpdate) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "App.UpdateProfile")
+	defer span.End()
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return models.User{}, ErrNoRUID
+	}
+	ctx = logger.WithDetails(ctx, "id", RUID)
+	err = a.Repo.UpdateProfile(ctx, RUID, upd)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.User{}, logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return models.User{}, logger.WrapError(ctx, err)
+	}
+	user, err := a.Repo.GetUserByID(ctx, RUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.User{}, logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return models.User{}, logger.WrapError(ctx, err)
+	}
+	return user, nil
+}
+
+// новая почта не записывается, пока не будет подтверждена по ссылке,
+// а на старую уходит уведомление о попытке смены
+func (a *App) RequestEmailChange(ctx context.Context, newEmail string) error {
+	ctx, span := tracing.Start(ctx, "App.RequestEmailChange")
+	defer span.End()
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return ErrNoRUID
+	}
+	user, err := a.Repo.GetUserByID(ctx, RUID)
+	if err != nil {
+		ctx = logger.WithDetails(ctx, "id", RUID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	ctx = logger.WithDetails(ctx, "new email", newEmail)
+	if user.Email == newEmail {
+		return logger.WrapError(ctx, ErrSameEmail)
+	}
+	_, err = a.Repo.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return logger.WrapError(ctx, ErrUserAlreadyExists)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return logger.WrapError(ctx, err)
+	}
+
+	err = a.Repo.SetPendingEmail(ctx, RUID, newEmail)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+
+	mailtoken := rand.Text()
+	link := fmt.Sprintf("%s/confirm_email_change/%s/%s", a.frontAddr, RUID, mailtoken)
+	msgID, err := a.Mail.SendEmailChangeMessage(user.Locale, RUID, newEmail, mailtoken, link)
+	if err != nil {
+		if errors.Is(err, mail.ErrMsgAlreadySent) {
+			return logger.WrapError(ctx, ErrMsgAlreadySent)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
+
+	msgID, err = a.Mail.SendEmailChangeNotice(user.Locale, user.Email, newEmail)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "mail malfunction", "error", err.Error())
+		return nil
+	}
+	a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
+	return nil
+}
+
+// ссылка на этот метод будет в письме, отправленном на новую почту
+func (a *App) ConfirmEmailChange(ctx context.Context, userID, mailtoken string) error {
+	ctx, span := tracing.Start(ctx, "App.ConfirmEmailChange")
+	defer span.End()
+	ctx = logger.WithDetails(ctx, "id", userID)
+	user, err := a.Repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	if user.PendingEmail == "" {
+		return logger.WrapError(ctx, ErrWrongMailToken)
+	}
+	ctx = logger.WithDetails(ctx, "new email", user.PendingEmail)
+	ok, err := a.Mail.CheckEmailChangeToken(userID, user.PendingEmail, mailtoken)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	if !ok {
+		return logger.WrapError(ctx, ErrWrongMailToken)
+	}
+	// ссылка из письма подтверждает, что действует сам пользователь
+	ctx = logger.WithUserID(ctx, userID)
+
+	err = a.Repo.ConfirmEmailChange(ctx, userID, user.PendingEmail)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return logger.WrapError(ctx, ErrUserAlreadyExists)
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrWrongMailToken)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	return nil
+}
+
 func (a *App) Login(ctx context.Context, email, barePassword string) (access string, refresh string, err error) {
+	ctx, span := tracing.Start(ctx, "App.Login")
+	defer span.End()
+	defer func() {
+		a.metrics.LoginAttempt(loginResult(err))
+	}()
+	ctx = logger.WithDetails(ctx, "email", email)
+	locked, err := a.claimLoginAttempt(ctx, email)
+	if err != nil {
+		return "", "", err
+	}
 	user, err := a.Repo.GetUserByEmail(ctx, email)
 	if err != nil {
-		ctx = logger.WithDetails(ctx, "email", email)
 		if errors.Is(err, repository.ErrNotFound) {
-			return "", "", logger.WrapError(ctx, ErrInvalidCredentials)
+			return "", "", a.loginFailed(ctx, email, "", "", locked)
 		}
 		return "", "", logger.WrapError(ctx, err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(barePassword))
+	err = comparePassword(ctx, user.HashedPassword, barePassword)
 	if err != nil {
-		return "", "", ErrInvalidCredentials
+		a.audit(ctx, models.AuditLoginFailed, user.ID)
+		return "", "", a.loginFailed(ctx, email, user.ID, user.Locale, locked)
+	}
+	ctx = logger.WithUserID(ctx, user.ID)
+	a.audit(ctx, models.AuditLogin, user.ID)
+	a.resetLoginFailures(ctx, email)
+	if user.IsDeletionScheduled {
+		ctx = logger.WithDetails(ctx, "id", user.ID)
+		canceled, err := a.Repo.CancelDeletion(ctx, user.ID)
+		if err != nil {
+			return "", "", logger.WrapError(ctx, err)
+		}
+		if canceled {
+			a.logger.InfoContext(ctx, "account deletion canceled by login")
+		}
 	}
-	access, err = a.CreateJWTToken(user.ID, user.Name, user.IsModer, user.IsAdmin, user.IsCore)
+	return a.startSession(ctx, models.UserToken{
+		ID:          user.ID,
+		Name:        user.Name,
+		Permissions: user.Permissions,
+	}, metrics.TokensLogin)
+}
+
+// для счетчика входов
+func loginResult(err error) string {
+	switch {
+	case err == nil:
+		return metrics.LoginSuccess
+	case errors.Is(err, ErrInvalidCredentials):
+		return metrics.LoginInvalidCredentials
+	case errors.Is(err, ErrAccountLocked):
+		return metrics.LoginLocked
+	case errors.Is(err, ErrLoginThrottled):
+		return metrics.LoginThrottled
+	default:
+		return metrics.LoginError
+	}
+}
+
+// каждый вход начинает новое семейство рефреш токенов, reason - для счетчика выданных токенов
+func (a *App) startSession(ctx context.Context, u models.UserToken, reason string) (access string, refresh string, err error) {
+	familyID, err := uuid.NewV7()
 	if err != nil {
 		return "", "", err
 	}
-	refresh, err = a.CreateRefreshToken(user.ID, user.Name, user.IsModer, user.IsAdmin, user.IsCore)
+	access, refresh, err = a.createTokenPair(u)
 	if err != nil {
 		return "", "", err
 	}
+	err = a.Repo.CreateRefreshToken(ctx, hashToken(refresh), u.ID, familyID.String(), time.Now().Add(a.refreshTTL))
+	if err != nil {
+		ctx = logger.WithDetails(ctx, "id", u.ID)
+		return "", "", logger.WrapError(ctx, err)
+	}
+	a.metrics.TokensIssued(reason)
 	return access, refresh, nil
 }
 
-func (a *App) IssueAccessFromRefresh(ctx context.Context, refresh string) (string, error) {
-	user, err := a.ParseJWTToken(refresh)
+func (a *App) createTokenPair(u models.UserToken) (access string, refresh string, err error) {
+	access, err = a.CreateAccessToken(u)
 	if err != nil {
-		// ошибку надо или логировать, или передавать выше, но тут для дебага и то, и другое оставлю
-		a.logger.InfoContext(ctx, "parse token", slog.String("error", err.Error()))
-		return "", ErrInvalidCredentials
+		return "", "", err
 	}
-	access, err := a.CreateJWTToken(user.ID, user.Name, user.IsModer, user.IsAdmin, user.IsCore)
+	refresh, err = a.CreateRefreshToken(u)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	return access, nil
+	return access, refresh, nil
 }
 
-// в будущем можно добавить отправку письма
-// в интерсепторе валидирую токен и кладу requestUserID в контекст
-// можно ли вынести эту проверку в интерсептор? -- deprecated
-
-// токен валидируется в http middleware
-// до сервиса додходит только userID из токена
-func (a *App) PromoteModer(ctx context.Context, userID string) error {
-	RUID, err := getRUID(ctx)
+// рефреш токен одноразовый: при обмене выдается новая пара, а старый помечается использованным.
+// повторное предъявление уже использованного токена означает, что его украли,
+// поэтому отзывается всё семейство - и у злоумышленника, и у пользователя
+func (a *App) RefreshTokens(ctx context.Context, refresh string) (access string, newRefresh string, err error) {
+	ctx, span := tracing.Start(ctx, "App.RefreshTokens")
+	defer span.End()
+	claims, err := a.ParseRefreshToken(refresh)
 	if err != nil {
-		return ErrNoRUID
+		ctx = logger.WithDetails(ctx, "parsing", err.Error())
+		return "", "", logger.WrapError(ctx, ErrInvalidRefreshToken)
 	}
-	_, err = a.Repo.GetAdmin(ctx, RUID)
+	ctx = logger.WithDetails(ctx, "id", claims.ID)
+
+	old, err := a.Repo.GetRefreshToken(ctx, hashToken(refresh))
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return ErrForbidden
+			return "", "", logger.WrapError(ctx, ErrInvalidRefreshToken)
 		}
-		return err
+		return "", "", logger.WrapError(ctx, err)
+	}
+	if old.IsRevoked || old.UserID != claims.ID || time.Now().After(old.ExpiresAt) {
+		return "", "", logger.WrapError(ctx, ErrInvalidRefreshToken)
+	}
+	if old.IsUsed {
+		return "", "", a.revokeFamily(ctx, old.FamilyID)
 	}
-	err = a.Repo.PromoteModer(ctx, userID)
+
+	// имя и права могли измениться с момента выпуска рефреш токена
+	user, err := a.Repo.GetUserTokenByID(ctx, claims.ID)
 	if err != nil {
-		ctx = logger.WithDetails(ctx, "id", userID)
 		if errors.Is(err, repository.ErrNotFound) {
-			return logger.WrapError(ctx, ErrUserNotFound)
+			return "", "", logger.WrapError(ctx, ErrInvalidRefreshToken)
 		}
-		if errors.Is(err, repository.ErrAlreadyExists) {
-			return logger.WrapError(ctx, ErrUserAlreadyExists)
+		return "", "", logger.WrapError(ctx, err)
+	}
+
+	access, newRefresh, err = a.createTokenPair(user)
+	if err != nil {
+		return "", "", err
+	}
+	err = a.Repo.RotateRefreshToken(ctx, old.Hash, models.RefreshToken{
+		Hash:      hashToken(newRefresh),
+		UserID:    user.ID,
+		FamilyID:  old.FamilyID,
+		ExpiresAt: time.Now().Add(a.refreshTTL),
+	})
+	if err != nil {
+		// токен успели использовать между чтением и ротацией
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", "", a.revokeFamily(ctx, old.FamilyID)
 		}
-		return logger.WrapError(ctx, err)
+		return "", "", logger.WrapError(ctx, err)
 	}
-	return nil
+	a.metrics.TokensIssued(metrics.TokensRefresh)
+	return access, newRefresh, nil
 }
 
-func (a *App) PromoteAdmin(ctx context.Context, userID string) error {
+// завершает текущую сессию: аксесс токен попадает в денайлист,
+// а семейство рефреш токенов этой сессии отзывается
+func (a *App) Logout(ctx context.Context, access, refresh string) error {
+	ctx, span := tracing.Start(ctx, "App.Logout")
+	defer span.End()
 	RUID, err := getRUID(ctx)
 	if err != nil {
 		return ErrNoRUID
 	}
-	//тут не доделал обработку ошибок с репозитория
-	admin, err := a.Repo.GetAdmin(ctx, RUID)
+	u, err := a.ParseAccessToken(access)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return ErrForbidden
-		}
-		return err
+		return ErrNoRUID
 	}
-	if !admin.IsCore {
-		return ErrForbidden
+	err = a.revokeAccessToken(u)
+	if err != nil {
+		return logger.WrapError(ctx, err)
 	}
-	//тут не доделал обработку ошибок с репозитория
-	err = a.Repo.PromoteAdmin(ctx, userID)
+
+	old, err := a.Repo.GetRefreshToken(ctx, hashToken(refresh))
 	if err != nil {
-		ctx = logger.WithDetails(ctx, "id", userID)
 		if errors.Is(err, repository.ErrNotFound) {
-			return logger.WrapError(ctx, ErrUserNotFound)
-		}
-		if errors.Is(err, repository.ErrAlreadyExists) {
-			return logger.WrapError(ctx, ErrUserAlreadyExists)
+			return logger.WrapError(ctx, ErrInvalidRefreshToken)
 		}
 		return logger.WrapError(ctx, err)
 	}
+	// чужую сессию завершить нельзя
+	if old.UserID != RUID {
+		return logger.WrapError(ctx, ErrInvalidRefreshToken)
+	}
+	err = a.Repo.RevokeRefreshTokenFamily(ctx, old.FamilyID)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
 	return nil
 }
 
-// специально разнесен с PromoteAdmin
-func (a *App) PromoteCoreAdmin(ctx context.Context, userID string) error {
+// завершает все сессии пользователя на всех устройствах
+func (a *App) LogoutAll(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "App.LogoutAll")
+	defer span.End()
 	RUID, err := getRUID(ctx)
 	if err != nil {
 		return ErrNoRUID
 	}
-	//тут не доделал обработку ошибок с репозитория
-	admin, err := a.Repo.GetAdmin(ctx, RUID)
+	return a.revokeAllSessions(ctx, RUID)
+}
+
+func (a *App) revokeAllSessions(ctx context.Context, userID string) error {
+	err := a.Repo.RevokeUserRefreshTokens(ctx, userID)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return ErrForbidden
-		}
-		return err
-	}
-	if !admin.IsCore {
-		return ErrForbidden
+		ctx = logger.WithDetails(ctx, "id", userID)
+		return logger.WrapError(ctx, err)
 	}
-	//тут не доделал обработку ошибок с репозитория
-	err = a.Repo.PromoteCoreAdmin(ctx, userID)
+	err = a.revokeUserAccessTokens(userID)
 	if err != nil {
 		ctx = logger.WithDetails(ctx, "id", userID)
-		if errors.Is(err, repository.ErrNotFound) {
-			return logger.WrapError(ctx, ErrUserNotFound)
-		}
 		return logger.WrapError(ctx, err)
 	}
 	return nil
 }
 
-// открыть страницу пользователя может только авторизованный пользователь
+func (a *App) revokeFamily(ctx context.Context, familyID string) error {
+	ctx = logger.WithDetails(ctx, "family", familyID)
+	a.logger.WarnContext(ctx, "refresh token reuse detected, revoking token family")
+	if err := a.Repo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	return logger.WrapError(ctx, ErrRefreshTokenReused)
+}
+
+// в профиле есть телефон и адрес, поэтому чужой профиль открывает только админ
 func (a *App) GetUserByID(ctx context.Context, userID string) (models.User, error) {
-	// RUID, ok := ctx.Value(vars.ContextKeyRequestUserID).(string)
-	// if !ok || RUID == "" {
-	// 	return models.User{}, myerrors.ErrInvalidCredentials
-	// }
+	ctx, span := tracing.Start(ctx, "App.GetUserByID")
+	defer span.End()
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return models.User{}, ErrNoRUID
+	}
+	if RUID != userID {
+		if err = a.Authorize(ctx, models.PermUsersRead); err != nil {
+			return models.User{}, err
+		}
+	}
 
-	//тут не доделал обработку ошибок с репозитория
 	user, err := a.Repo.GetUserByID(ctx, userID)
 	if err != nil {
 		ctx = logger.WithDetails(ctx, "id", userID)
@@ -339,15 +632,9 @@ func (a *App) GetUserByID(ctx context.Context, userID string) (models.User, erro
 
 // когда не админ, редирект на свою страницу
 func (a *App) GetUsersByEmail(ctx context.Context, email string) ([]models.UserInfo, error) {
-	RUID, err := getRUID(ctx)
-	if err != nil {
-		return nil, ErrNoRUID
-	}
-	_, err = a.Repo.GetAdmin(ctx, RUID)
-	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrForbidden
-		}
+	ctx, span := tracing.Start(ctx, "App.GetUsersByEmail")
+	defer span.End()
+	if err := a.Authorize(ctx, models.PermUsersRead); err != nil {
 		return nil, err
 	}
 	users, err := a.Repo.GetUsersByEmail(ctx, email)
@@ -361,92 +648,245 @@ func (a *App) GetUsersByEmail(ctx context.Context, email string) ([]models.UserI
 	return users, nil
 }
 
-func (a *App) ChangePasswordRequest(ctx context.Context) error {
-	// RUID, ok := ctx.Value(vars.ContextKeyRequestUserID).(string)
-	// if !ok || RUID == "" {
-	// 	return myerrors.ErrInvalidCredentials
-	// }
-	// генерация токена и сохранение его в кэше вместе с userID
-	//
-	// отправка письма с ссылкой на смену пароля
-	// ссылка типа /reset_password/<uid>/<token>, где токен хранится в кэше в паре userID : token
-	// и при переходе по ссылке посмотреть, валиден ли токен для этого пользователя
-	// и если да, то разрешить смену пароля
-	//
+// ответ не должен выдавать, зарегистрирована ли почта, поэтому
+// отсутствие пользователя и повторный запрос только логируются
+func (a *App) ResetPasswordRequest(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "App.ResetPasswordRequest")
+	defer span.End()
+	ctx = logger.WithDetails(ctx, "email", email)
+	user, err := a.Repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			a.logger.InfoContext(ctx, "password reset requested for unknown email")
+			return nil
+		}
+		return logger.WrapError(ctx, err)
+	}
+
+	// ссылка типа /reset_password/<uid>/<token>, где хэш токена хранится в БД под ключом reset:<uid>
+	resettoken := rand.Text()
+	link := fmt.Sprintf("%s/reset_password/%s/%s", a.frontAddr, user.ID, resettoken)
+	msgID, err := a.Mail.SendPasswordResetMessage(user.Locale, user.ID, email, resettoken, link)
+	if err != nil {
+		if errors.Is(err, mail.ErrMsgAlreadySent) {
+			a.logger.InfoContext(ctx, "password reset message has already been sent")
+			return nil
+		}
+		a.logger.ErrorContext(ctx, "mail malfunction", "error", err.Error())
+		return nil
+	}
+	a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
 	return nil
 }
 
-func (a *App) ResetPasswordRequest(ctx context.Context, email string) error {
-	_, err := a.Repo.GetUserByEmail(ctx, email)
+// ссылка на этот метод будет в письме, токен одноразовый и удаляется только после смены пароля.
+// после смены пароля все сессии завершаются, ведь сброс мог понадобиться из-за кражи пароля
+func (a *App) ResetPassword(ctx context.Context, userID, resettoken, newBarePassword string) error {
+	ctx, span := tracing.Start(ctx, "App.ResetPassword")
+	defer span.End()
+	ctx = logger.WithDetails(ctx, "id", userID)
+	ok, err := a.Mail.ValidResetToken(userID, resettoken)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	if !ok {
+		return logger.WrapError(ctx, ErrWrongMailToken)
+	}
+	ctx = logger.WithUserID(ctx, userID)
+	profile, err := a.Repo.GetUserByID(ctx, userID)
 	if err != nil {
-		ctx = logger.WithDetails(ctx, "email", email)
 		if errors.Is(err, repository.ErrNotFound) {
 			return logger.WrapError(ctx, ErrUserNotFound)
 		}
 		return logger.WrapError(ctx, err)
 	}
-	// генерация токена и сохранение его в кэше вместе с userID
-	//
-	// отправка письма с ссылкой на сброс пароля
-	// ссылка типа /reset_password/<uid>/<token>, где токен хранится в кэше в паре userID : token
-	// и при переходе по ссылке посмотреть, валиден ли токен для этого пользователя
-	// и если да, то разрешить сброс пароля
-	//
+	ctx = logger.WithDetails(ctx, "email", profile.Email)
+	user, err := a.Repo.GetUserByEmail(ctx, profile.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	err = a.setPassword(ctx, profile, user.HashedPassword, newBarePassword)
+	if err != nil {
+		return err
+	}
+	// пароль уже сменен, а токен истечет сам, поэтому ошибка только логируется
+	if err = a.Mail.DeleteResetToken(userID); err != nil {
+		a.logger.ErrorContext(ctx, "reset token deletion failed", "error", err.Error())
+	}
 	return nil
 }
 
-// в любом случае приходит ссылка на почту,
-// поэтому нет смысла запрашивать старый пароль
-func (a *App) ChangePassword(ctx context.Context, newBarePassword string) error {
+// сколько последних паролей, включая текущий, нельзя использовать повторно
+const passwordHistorySize = 5
+
+// смена пароля из личного кабинета, поэтому требуется текущий пароль.
+// все остальные сессии завершаются, а текущая получает новую пару токенов
+func (a *App) ChangePassword(ctx context.Context, currentBarePassword, newBarePassword string) (access string, refresh string, err error) {
+	ctx, span := tracing.Start(ctx, "App.ChangePassword")
+	defer span.End()
 	RUID, err := getRUID(ctx)
 	if err != nil {
-		return ErrNoRUID
+		return "", "", ErrNoRUID
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newBarePassword), bcrypt.DefaultCost)
+	profile, err := a.Repo.GetUserByID(ctx, RUID)
 	if err != nil {
-		return err
+		ctx = logger.WithDetails(ctx, "id", RUID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", "", logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return "", "", logger.WrapError(ctx, err)
 	}
-	err = a.Repo.ChangePassword(ctx, RUID, string(hashedPassword))
+	ctx = logger.WithDetails(ctx, "email", profile.Email)
+	user, err := a.Repo.GetUserByEmail(ctx, profile.Email)
 	if err != nil {
-		return err
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", "", logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return "", "", logger.WrapError(ctx, err)
 	}
-	return nil
+	err = comparePassword(ctx, user.HashedPassword, currentBarePassword)
+	if err != nil {
+		return "", "", logger.WrapError(ctx, ErrWrongPassword)
+	}
+
+	err = a.setPassword(ctx, profile, user.HashedPassword, newBarePassword)
+	if err != nil {
+		return "", "", err
+	}
+	return a.startSession(ctx, models.UserToken{
+		ID:          user.ID,
+		Name:        user.Name,
+		Permissions: user.Permissions,
+	}, metrics.TokensPasswordChange)
+}
+
+// общая часть смены и сброса пароля: новый пароль не должен совпадать с последними
+// passwordHistorySize, владельцу уходит уведомление, все сессии завершаются
+func (a *App) setPassword(ctx context.Context, profile models.User, currentHash, newBarePassword string) error {
+	history, err := a.Repo.GetPasswordHistory(ctx, profile.ID, passwordHistorySize-1)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	for _, old := range append(history, currentHash) {
+		if comparePassword(ctx, old, newBarePassword) == nil {
+			return logger.WrapError(ctx, ErrPasswordReused)
+		}
+	}
+
+	hashedPassword, err := a.hashPassword(ctx, newBarePassword)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	err = a.Repo.ChangePassword(ctx, profile.ID, string(hashedPassword))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+
+	msgID, err := a.Mail.SendPasswordChangedMessage(profile.Locale, profile.Email)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "mail malfunction", "error", err.Error())
+	} else {
+		a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
+	}
+
+	return a.revokeAllSessions(ctx, profile.ID)
 }
 
 // ----------------------------------------------------------------------
 // ДЛЯ ДРУГИХ СЕРВИСОВ
 // ----------------------------------------------------------------------
 
+// вызывается сервисами, а не пользователями, поэтому RUID не проверяется.
+// отсутствие прав - не ошибка, а ответ
 func (a *App) IsAdmin(ctx context.Context, userID string) (bool, error) {
-	//тут не доделал обработку ошибок с репозитория
-	_, err := a.Repo.GetAdmin(ctx, userID)
+	ctx, span := tracing.Start(ctx, "App.IsAdmin")
+	defer span.End()
+	return a.hasRole(ctx, userID, models.RoleAdmin)
+}
+
+func (a *App) IsModer(ctx context.Context, userID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "App.IsModer")
+	defer span.End()
+	return a.hasRole(ctx, userID, models.RoleModer)
+}
+
+func (a *App) hasRole(ctx context.Context, userID, role string) (bool, error) {
+	roles, err := a.Repo.GetUserRoles(ctx, userID)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return false, ErrForbidden
-		}
-		return false, err
+		return false, logger.WrapError(logger.WithDetails(ctx, "id", userID), err)
 	}
-	return true, nil
+	return slices.Contains(roles, role), nil
 }
 
-func (a *App) IsModer(ctx context.Context, userID string) (bool, error) {
-	//тут не доделал обработку ошибок с репозитория
-	_, err := a.Repo.GetModer(ctx, userID)
+func (a *App) CheckPermissions(ctx context.Context, userID string) (models.Permissions, error) {
+	ctx, span := tracing.Start(ctx, "App.CheckPermissions")
+	defer span.End()
+	ps, err := a.CheckPermissionsBatch(ctx, []string{userID})
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return false, ErrForbidden
+		return models.Permissions{}, err
+	}
+	if !ps[0].Found {
+		return models.Permissions{}, logger.WrapError(logger.WithDetails(ctx, "id", userID), ErrUserNotFound)
+	}
+	return ps[0], nil
+}
+
+// результат в порядке userIDs, у несуществующих пользователей Found = false
+func (a *App) CheckPermissionsBatch(ctx context.Context, userIDs []string) ([]models.Permissions, error) {
+	ctx, span := tracing.Start(ctx, "App.CheckPermissionsBatch")
+	defer span.End()
+	found, err := a.Repo.GetPermissions(ctx, userIDs)
+	if err != nil {
+		return nil, logger.WrapError(logger.WithDetails(ctx, "ids", userIDs), err)
+	}
+	byID := make(map[string]models.Permissions, len(found))
+	for _, p := range found {
+		byID[p.UserID] = p
+	}
+	res := make([]models.Permissions, 0, len(userIDs))
+	for _, id := range userIDs {
+		p, ok := byID[id]
+		if !ok {
+			p = models.Permissions{UserID: id}
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// текущий ключ подписи
+func (a *App) GetRSAPublicKey() (models.JWK, error) {
+	kid, private := a.keys.SigningKey()
+	return toJWK(keys.PublicKey{ID: kid, Key: &private.PublicKey})
+}
+
+// все ключи, которыми сейчас можно проверить токен
+func (a *App) GetJWKS() ([]models.JWK, error) {
+	pubs := a.keys.PublicKeys()
+	res := make([]models.JWK, 0, len(pubs))
+	for _, pub := range pubs {
+		jwk, err := toJWK(pub)
+		if err != nil {
+			return nil, err
 		}
-		return false, err
+		res = append(res, jwk)
 	}
-	return true, nil
+	return res, nil
 }
 
-func (a *App) GetRSAPublicKey() ([]byte, error) {
-	der, err := x509.MarshalPKIXPublicKey(a.publicKey)
+func toJWK(pub keys.PublicKey) (models.JWK, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub.Key)
 	if err != nil {
-		return nil, err
+		return models.JWK{}, err
 	}
 	res := make([]byte, base64.StdEncoding.Strict().EncodedLen(len(der)))
 	base64.StdEncoding.Encode(res, der)
-	return res, nil
+	n, e := keys.Components(pub.Key)
+	return models.JWK{ID: pub.ID, N: n, E: e, PKIX: res}, nil
 }
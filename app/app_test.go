@@ -0,0 +1,337 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/keys"
+	"github.com/glekoz/online-shop_user/metrics"
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// тесты App работают с фейками вместо БД и почты. фейк реализует только методы,
+// которые нужны тестам, вызов остальных паникует на встроенном nil интерфейсе
+
+// генерация RSA ключа долгая, поэтому ключ один на все тесты
+var testKeys *keys.Manager
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "app-keys")
+	if err != nil {
+		panic(err)
+	}
+	testKeys, err = keys.FromDir(dir, 30*24*time.Hour, 48*time.Hour, 15*time.Minute, slog.New(slog.DiscardHandler))
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testAppConfig = config.App{
+	FrontAddr:       "https://shop.example.com",
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 24 * time.Hour,
+	BcryptCost:      bcrypt.MinCost,
+	DeletionGrace:   30 * 24 * time.Hour,
+}
+
+func newTestApp(t *testing.T) (*App, *fakeRepo, *fakeMail) {
+	t.Helper()
+	repo := &fakeRepo{refresh: make(map[string]*models.RefreshToken)}
+	mail := &fakeMail{}
+	a := New(repo, mail, newFakeDenylist(), slog.New(slog.DiscardHandler), testKeys, noMetrics{}, testAppConfig)
+	return a, repo, mail
+}
+
+type fakeRepo struct {
+	RepoAPI
+
+	mu      sync.Mutex
+	users   []*fakeUser
+	refresh map[string]*models.RefreshToken // ключ - хэш
+
+	failures    map[loginKeyIP]*models.LoginFailures
+	loginErr    error // ошибка ClaimLoginAttempt
+	failuresErr error // ошибка GetLoginFailures
+	audit       []string
+
+	// вызывается перед ротацией рефреш токена, чтобы воспроизвести гонку
+	beforeRotate func()
+	// вызывается перед снятием роли, чтобы воспроизвести одновременное разжалование
+	beforeRevoke func()
+}
+
+type fakeUser struct {
+	models.User
+	hash    string
+	roles   []string
+	deleted bool
+}
+
+func (r *fakeRepo) addUser(id, name, email string) *fakeUser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := &fakeUser{User: models.User{ID: id, Name: name, Email: email, Locale: models.DefaultLocale}}
+	r.users = append(r.users, u)
+	return u
+}
+
+// удаленные пользователи не находятся, как и в запросах репозитория
+func (r *fakeRepo) userByID(id string) *fakeUser {
+	for _, u := range r.users {
+		if u.ID == id && !u.deleted {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(id)
+	if u == nil {
+		return models.User{}, repository.ErrNotFound
+	}
+	return u.User, nil
+}
+
+func (r *fakeRepo) GetUserTokenByID(ctx context.Context, id string) (models.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(id)
+	if u == nil {
+		return models.UserToken{}, repository.ErrNotFound
+	}
+	return models.UserToken{ID: u.ID, Name: u.Name}, nil
+}
+
+func (r *fakeRepo) CreateRefreshToken(ctx context.Context, hash, userID, familyID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.refresh[hash]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.refresh[hash] = &models.RefreshToken{Hash: hash, UserID: userID, FamilyID: familyID, ExpiresAt: expiresAt}
+	return nil
+}
+
+func (r *fakeRepo) GetRefreshToken(ctx context.Context, hash string) (models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.refresh[hash]
+	if !ok {
+		return models.RefreshToken{}, repository.ErrNotFound
+	}
+	return *t, nil
+}
+
+// как UseRefreshToken: использованный или отозванный токен не ротируется
+func (r *fakeRepo) RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) error {
+	if r.beforeRotate != nil {
+		r.beforeRotate()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.refresh[oldHash]
+	if !ok || old.IsUsed || old.IsRevoked {
+		return repository.ErrNotFound
+	}
+	old.IsUsed = true
+	r.refresh[next.Hash] = &next
+	return nil
+}
+
+func (r *fakeRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refresh {
+		if t.FamilyID == familyID {
+			t.IsRevoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refresh {
+		if t.UserID == userID {
+			t.IsRevoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) refreshToken(token string) models.RefreshToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.refresh[hashToken(token)]
+}
+
+func (r *fakeRepo) updateRefreshToken(token string, f func(t *models.RefreshToken)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r.refresh[hashToken(token)])
+}
+
+type fakeMail struct {
+	MailAPI
+
+	mu     sync.Mutex
+	unlock map[string]string // токены разблокировки по id пользователя
+}
+
+// денайлист без истечения записей: тесты короче любого ttl
+type fakeDenylist struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newFakeDenylist() *fakeDenylist {
+	return &fakeDenylist{m: make(map[string]string)}
+}
+
+func (d *fakeDenylist) AddWithTTL(key, value string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.m[key] = value
+	return nil
+}
+
+func (d *fakeDenylist) Get(key string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, ok := d.m[key]
+	return v, ok
+}
+
+type noMetrics struct{}
+
+func (noMetrics) Registered()         {}
+func (noMetrics) LoginAttempt(string) {}
+func (noMetrics) TokensIssued(string) {}
+func (noMetrics) MailDelivery(string) {}
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	session := func(t *testing.T) (*App, *fakeRepo, string) {
+		t.Helper()
+		a, repo, _ := newTestApp(t)
+		repo.addUser("u1", "Ivan", "ivan@example.com")
+		_, refresh, err := a.startSession(ctx, models.UserToken{ID: "u1", Name: "Ivan"}, metrics.TokensLogin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a, repo, refresh
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		a, repo, refresh := session(t)
+		access, next, err := a.RefreshTokens(ctx, refresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if access == "" || next == "" || next == refresh {
+			t.Fatal("no new token pair")
+		}
+		if !repo.refreshToken(refresh).IsUsed {
+			t.Error("old token is not marked as used")
+		}
+		if repo.refreshToken(next).FamilyID != repo.refreshToken(refresh).FamilyID {
+			t.Error("new token is in another family")
+		}
+		if _, _, err = a.RefreshTokens(ctx, next); err != nil {
+			t.Errorf("new token is not accepted: %v", err)
+		}
+	})
+
+	t.Run("replay revokes family", func(t *testing.T) {
+		a, repo, refresh := session(t)
+		_, next, err := a.RefreshTokens(ctx, refresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = a.RefreshTokens(ctx, refresh); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("replay: %v, want ErrRefreshTokenReused", err)
+		}
+		if !repo.refreshToken(next).IsRevoked {
+			t.Error("token issued by rotation is not revoked")
+		}
+		if _, _, err = a.RefreshTokens(ctx, next); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("revoked family token: %v, want ErrInvalidRefreshToken", err)
+		}
+	})
+
+	t.Run("concurrent rotation", func(t *testing.T) {
+		a, repo, refresh := session(t)
+		// другой запрос успел использовать токен между чтением и ротацией
+		repo.beforeRotate = func() {
+			repo.updateRefreshToken(refresh, func(t *models.RefreshToken) { t.IsUsed = true })
+		}
+		if _, _, err := a.RefreshTokens(ctx, refresh); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("%v, want ErrRefreshTokenReused", err)
+		}
+		if !repo.refreshToken(refresh).IsRevoked {
+			t.Error("family is not revoked")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		a, repo, refresh := session(t)
+		repo.updateRefreshToken(refresh, func(t *models.RefreshToken) { t.ExpiresAt = time.Now().Add(-time.Minute) })
+		if _, _, err := a.RefreshTokens(ctx, refresh); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("%v, want ErrInvalidRefreshToken", err)
+		}
+		if repo.refreshToken(refresh).IsUsed {
+			t.Error("expired token is rotated")
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		a, repo, refresh := session(t)
+		if err := repo.RevokeUserRefreshTokens(ctx, "u1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := a.RefreshTokens(ctx, refresh); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("%v, want ErrInvalidRefreshToken", err)
+		}
+	})
+
+	t.Run("unknown or malformed", func(t *testing.T) {
+		a, _, _ := session(t)
+		other, _, _ := newTestApp(t)
+		// подпись верна, но токена нет в БД
+		stray, err := other.CreateRefreshToken(models.UserToken{ID: "u1", Name: "Ivan"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, token := range []string{stray, "not a token"} {
+			if _, _, err = a.RefreshTokens(ctx, token); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("%v, want ErrInvalidRefreshToken", err)
+			}
+		}
+	})
+
+	t.Run("access token is not a refresh token", func(t *testing.T) {
+		a, _, _ := session(t)
+		access, err := a.CreateAccessToken(models.UserToken{ID: "u1", Name: "Ivan"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = a.RefreshTokens(ctx, access); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%v, want ErrInvalidRefreshToken", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+)
+
+const DefaultAuditPageSize = 50
+
+// для событий без изменений в БД: запись в журнал не должна мешать самому действию,
+// поэтому ошибка только логируется. изменения пишут журнал в своей транзакции в репозитории
+func (a *App) audit(ctx context.Context, action, targetID string) {
+	err := a.Repo.AddAuditEntry(ctx, action, targetID, nil, nil)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "audit entry not written", "action", action, "error", err.Error())
+	}
+}
+
+// страницы идут от новых записей к старым, пустой nextCursor - страниц больше нет
+func (a *App) GetAuditLog(ctx context.Context, f models.AuditFilter, cursor string) (entries []models.AuditEntry, nextCursor string, err error) {
+	ctx, span := tracing.Start(ctx, "App.GetAuditLog")
+	defer span.End()
+	if err = a.Authorize(ctx, models.PermAuditRead); err != nil {
+		return nil, "", err
+	}
+	if cursor != "" {
+		f.BeforeID, err = decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, "", logger.WrapError(logger.WithDetails(ctx, "cursor", cursor), ErrInvalidCursor)
+		}
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultAuditPageSize
+	}
+	limit := f.Limit
+	f.Limit++ // лишняя запись показывает, есть ли следующая страница
+	entries, err = a.Repo.GetAuditLog(ctx, f)
+	if err != nil {
+		return nil, "", logger.WrapError(ctx, err)
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		nextCursor = encodeAuditCursor(entries[limit-1].ID)
+	}
+	return entries, nextCursor, nil
+}
+
+// курсор непрозрачен для клиента, внутри id последней записи страницы
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+)
+
+// сколько аккаунтов анонимизируется за один проход фоновой задачи
+const deletionBatchSize = 100
+
+// удаление откладывается на deletionGrace, все сессии завершаются сразу,
+// а вход в аккаунт до истечения срока отменяет удаление
+func (a *App) DeleteAccount(ctx context.Context, barePassword string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "App.DeleteAccount")
+	defer span.End()
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return time.Time{}, ErrNoRUID
+	}
+	ctx = logger.WithDetails(ctx, "id", RUID)
+	profile, err := a.Repo.GetUserByID(ctx, RUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return time.Time{}, logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return time.Time{}, logger.WrapError(ctx, err)
+	}
+	user, err := a.Repo.GetUserByEmail(ctx, profile.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return time.Time{}, logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return time.Time{}, logger.WrapError(ctx, err)
+	}
+	err = comparePassword(ctx, user.HashedPassword, barePassword)
+	if err != nil {
+		return time.Time{}, logger.WrapError(ctx, ErrWrongPassword)
+	}
+
+	at := time.Now().Add(a.deletionGrace)
+	err = a.Repo.ScheduleDeletion(ctx, RUID, at)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return time.Time{}, logger.WrapError(ctx, ErrUserNotFound)
+		case errors.Is(err, repository.ErrLastCoreAdmin):
+			return time.Time{}, logger.WrapError(ctx, ErrLastCoreAdmin)
+		default:
+			return time.Time{}, logger.WrapError(ctx, err)
+		}
+	}
+	err = a.revokeAllSessions(ctx, RUID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	msgID, err := a.Mail.SendAccountDeletionMessage(profile.Locale, profile.Email, at)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "mail malfunction", "error", err.Error())
+	} else {
+		a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
+	}
+	a.logger.InfoContext(ctx, "account deletion scheduled", "at", at)
+	return at, nil
+}
+
+// немедленное удаление администратором, удалить админа может только тот,
+// кто может управлять админами
+func (a *App) AdminDeleteUser(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.AdminDeleteUser")
+	defer span.End()
+	if err := a.Authorize(ctx, models.PermUsersDelete); err != nil {
+		return err
+	}
+	roles, err := a.Repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return logger.WrapError(logger.WithDetails(ctx, "id", userID), err)
+	}
+	switch {
+	case slices.Contains(roles, models.RoleCoreAdmin): // core админа сначала надо разжаловать
+		return ErrForbidden
+	case slices.Contains(roles, models.RoleAdmin):
+		if err = a.Authorize(ctx, models.PermAdminsManage); err != nil {
+			return err
+		}
+	}
+	return a.deleteAccount(ctx, userID)
+}
+
+// RunAccountDeletion анонимизирует аккаунты, у которых истек срок ожидания удаления.
+// Работает, пока не отменен ctx
+func (a *App) RunAccountDeletion(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ids, err := a.Repo.GetDueDeletions(ctx, deletionBatchSize)
+		if err != nil {
+			a.logger.ErrorContext(ctx, "due deletions fetching failed", "error", err.Error())
+			continue
+		}
+		for _, id := range ids {
+			err = a.deleteAccount(ctx, id)
+			if errors.Is(err, ErrLastCoreAdmin) {
+				a.blockDeletion(ctx, id)
+				continue
+			}
+			if err != nil {
+				a.logger.ErrorContext(logger.ErrorCtx(ctx, err), "account deletion failed", "error", err.Error())
+			}
+		}
+	}
+}
+
+// пользователь мог стать последним core админом уже после запроса удаления,
+// когда разжаловали остальных. удаление отменяется, иначе оно повторялось бы
+// на каждом проходе
+func (a *App) blockDeletion(ctx context.Context, userID string) {
+	ctx = logger.WithDetails(ctx, "id", userID)
+	if _, err := a.Repo.CancelDeletion(ctx, userID); err != nil {
+		a.logger.ErrorContext(ctx, "blocked deletion canceling failed", "error", err.Error())
+		return
+	}
+	a.logger.WarnContext(ctx, "account deletion canceled: the last core admin can't be deleted")
+}
+
+func (a *App) deleteAccount(ctx context.Context, userID string) error {
+	ctx = logger.WithDetails(ctx, "id", userID)
+	err := a.Repo.AnonymizeUser(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return logger.WrapError(ctx, ErrUserNotFound)
+		case errors.Is(err, repository.ErrLastCoreAdmin):
+			return logger.WrapError(ctx, ErrLastCoreAdmin)
+		default:
+			return logger.WrapError(ctx, err)
+		}
+	}
+	// рефреш токены отозваны, а токены из писем удалены в транзакции, остаются аксесс токены
+	err = a.revokeUserAccessTokens(userID)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	a.logger.InfoContext(ctx, "account deleted")
+	return nil
+}
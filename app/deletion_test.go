@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+func (r *fakeRepo) AnonymizeUser(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(id)
+	if u == nil {
+		return repository.ErrNotFound
+	}
+	u.deleted = true
+	u.Email = "deleted-" + id + "@deleted.invalid"
+	return nil
+}
+
+func TestDeleteAccount(t *testing.T) {
+	a, repo, _ := newTestApp(t)
+	repo.addUser("u1", "Ivan", "ivan@example.com")
+	ctx := context.Background()
+	access, err := a.CreateAccessToken(models.UserToken{ID: "u1", Name: "Ivan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.deleteAccount(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Repo.GetUserByID(ctx, "u1"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("deleted user is found: %v", err)
+	}
+	if _, err = a.ParseAccessToken(access); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("access token issued before deletion: %v, want ErrTokenRevoked", err)
+	}
+	if err = a.deleteAccount(ctx, "u1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second deletion: %v, want ErrUserNotFound", err)
+	}
+}
@@ -9,9 +9,25 @@ var (
 	ErrMsgAlreadySent        = errors.New("message is already sent")
 	ErrEmailAlreadyConfirmed = errors.New("email has already been confirmed")
 	ErrWrongMailToken        = errors.New("provided token does not exist")
+	ErrSameEmail             = errors.New("new email is the same as the current one")
 
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrLoginThrottled     = errors.New("too many failed login attempts")
+	ErrAccountLocked      = errors.New("account is locked after too many failed login attempts")
+	ErrWrongPassword      = errors.New("current password is wrong")
+	ErrPasswordReused     = errors.New("password has been used recently")
 	ErrForbidden          = errors.New("not authorized")
+	ErrInvalidCursor      = errors.New("invalid pagination cursor")
+	ErrRoleAlreadyGranted = errors.New("user already has this role")
+	ErrSelfDemotion       = errors.New("user can't demote themselves")
+	ErrSelfBan            = errors.New("user can't ban themselves")
+	ErrHasHigherRole      = errors.New("user has a higher role that must be removed first")
+	ErrLastCoreAdmin      = errors.New("the last core admin can't be demoted or deleted")
+
+	ErrWrongTokenType      = errors.New("wrong token type")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid, expired or revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
 
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrUserNotFound      = errors.New("user not found")
@@ -2,8 +2,12 @@ package app
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 
 	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/tracing"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // в интерцепторе уже делается так, чтобы айди не был пустым
@@ -16,3 +20,30 @@ func getRUID(ctx context.Context) (string, error) {
 	}
 	return ld.UserID, nil
 }
+
+// в БД рефреш токены хранятся только в виде хэша, чтобы утечка таблицы
+// не давала готовых токенов
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// адрес клиента кладет в контекст RateLimiter
+func getIP(ctx context.Context) string {
+	ld, _ := ctx.Value(logger.LogDataKey).(logger.LogData)
+	return ld.IPAddress
+}
+
+// bcrypt - самая долгая часть входа и регистрации, поэтому у него свой спан
+func (a *App) hashPassword(ctx context.Context, barePassword string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+	return bcrypt.GenerateFromPassword([]byte(barePassword), a.bcryptCost)
+}
+
+// несовпадение пароля не отмечается в спане ошибкой
+func comparePassword(ctx context.Context, hashedPassword, barePassword string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(barePassword))
+}
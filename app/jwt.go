@@ -1,77 +1,226 @@
 package app
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/glekoz/online-shop_user/shared/models"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// type data struct {
-// 	ID      string
-// 	Name    string
-// 	IsModer bool
-// 	IsAdmin bool
-// 	IsCore  bool
-// }
-
-// время жизни токена вынести в конфиг 24*time.Hour
-func (a *App) CreateJWTToken(userID, name string, isModer, isAdmin, isCore bool) (string, error) {
-	return a.createToken(userID, name, isModer, isAdmin, isCore, 15*time.Minute)
+// Формат токенов, выпускаемых сервисом.
+// Другие сервисы проверяют их публичным ключом из GetRSAPublicKey, поэтому
+// любое несовместимое изменение клаймов должно увеличивать TokenVersion.
+//
+// Версия 3 (текущая), алгоритм RS384, в заголовке kid ключа подписи из GetJWKS:
+//
+//	iss   - всегда "online-shop_user"
+//	sub   - id пользователя
+//	aud   - кому предназначен токен: AccessAudience или RefreshAudience
+//	typ   - вид токена: TokenTypeAccess или TokenTypeRefresh
+//	ver   - версия формата клаймов
+//	iat, nbf, exp - время выпуска, начала и конца действия
+//	jti   - уникальный id токена
+//	name  - имя пользователя
+//	perms - права пользователя на момент выпуска, строки вида "comments:delete",
+//	        отсортированы, у обычного пользователя клайм отсутствует
+//
+// Версия 2 вместо perms хранила роли в булевых клаймах moder, admin и core.
+// Версия 1 (без поля ver) хранила данные пользователя в клайме "data"
+// и не различала аксесс и рефреш токены. Токены старых версий не принимаются.
+const (
+	TokenIssuer  = "online-shop_user"
+	TokenVersion = 3
+
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	// аксесс токен принимают все сервисы магазина,
+	// а рефреш токен - только этот сервис
+	AccessAudience  = "online-shop"
+	RefreshAudience = "online-shop_user"
+)
+
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	Type        string   `json:"typ"`
+	Version     int      `json:"ver"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"perms,omitempty"`
+}
+
+func (c *TokenClaims) UserToken() models.UserToken {
+	return models.UserToken{
+		ID:          c.Subject,
+		Name:        c.Name,
+		Permissions: c.Permissions,
+
+		TokenID:   c.ID,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiresAt: c.ExpiresAt.Time,
+	}
+}
+
+// время жизни токенов задается в config.App
+func (a *App) CreateAccessToken(u models.UserToken) (string, error) {
+	return a.createToken(u, TokenTypeAccess, AccessAudience, a.accessTTL)
 }
 
-// время жизни токена вынести в конфиг 15*time.Minute
-func (a *App) CreateRefreshToken(userID, name string, isModer, isAdmin, isCore bool) (string, error) {
-	return a.createToken(userID, name, isModer, isAdmin, isCore, 24*time.Hour)
+func (a *App) CreateRefreshToken(u models.UserToken) (string, error) {
+	return a.createToken(u, TokenTypeRefresh, RefreshAudience, a.refreshTTL)
 }
 
-// возможно, токен будет парситься в http middleware
-// любая ошибка говорит о том, что что-то не так с токеном
-func (a *App) ParseJWTToken(tokenString string) (models.UserToken, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (any, error) {
-		return a.publicKey, nil
-	})
+// любая ошибка говорит о том, что что-то не так с токеном.
+// кроме подписи проверяется, не был ли токен отозван при выходе из аккаунта
+func (a *App) ParseAccessToken(tokenString string) (models.UserToken, error) {
+	claims, err := a.parseToken(tokenString, TokenTypeAccess, AccessAudience)
 	if err != nil {
 		return models.UserToken{}, err
 	}
-	if !token.Valid {
-		return models.UserToken{}, errors.New("!token.Valid")
-	}
-	claims, ok := token.Claims.(*jwt.MapClaims)
-	if !ok {
-		return models.UserToken{}, errors.New("token.Claims.(*jwt.MapClaims)")
-	}
-	data, ok := (*claims)["data"].(map[string]any)
-	if !ok {
-		return models.UserToken{}, errors.New("(*claims)[data].(map[string]any)")
+	u := claims.UserToken()
+	if a.isAccessTokenRevoked(u) {
+		return models.UserToken{}, ErrTokenRevoked
 	}
-	user, err := models.MapToToken(data)
+	return u, nil
+}
+
+func (a *App) ParseRefreshToken(tokenString string) (models.UserToken, error) {
+	claims, err := a.parseToken(tokenString, TokenTypeRefresh, RefreshAudience)
 	if err != nil {
 		return models.UserToken{}, err
 	}
-	return user, nil
+	return claims.UserToken(), nil
 }
 
-func (a *App) createToken(userID, name string, isModer, isAdmin, isCore bool, duration time.Duration) (string, error) {
-	data := map[string]any{
-		"ID":      userID,
-		"Name":    name,
-		"IsModer": isModer,
-		"IsAdmin": isAdmin,
-		"IsCore":  isCore,
+func (a *App) parseToken(tokenString, typ, audience string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("no kid in token header")
+		}
+		key, ok := a.keys.PublicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %s", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS384.Alg()}),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("!token.Valid")
+	}
+	if claims.Version != TokenVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrWrongTokenType, claims.Version)
+	}
+	if claims.Type != typ {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrWrongTokenType, typ, claims.Type)
+	}
+	if claims.Subject == "" || claims.Name == "" || claims.ID == "" {
+		return nil, errors.New("sub, name or jti is empty")
+	}
+	return claims, nil
+}
 
-	claims := &jwt.MapClaims{
-		"iss":  "online-shop_user",
-		"exp":  jwt.NewNumericDate(time.Now().Add(duration)),
-		"data": data,
+func (a *App) createToken(u models.UserToken, typ, audience string, duration time.Duration) (string, error) {
+	now := time.Now()
+	// jti делает каждый токен уникальным, иначе два рефреш токена,
+	// выданные в одну секунду, совпадут вместе с хэшем в БД.
+	// v7, чтобы сравнивать время выпуска с отзывом всех токенов, см. isAccessTokenRevoked
+	jti, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	claims := &TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   u.ID,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti.String(),
+		},
+		Type:        typ,
+		Version:     TokenVersion,
+		Name:        u.Name,
+		Permissions: u.Permissions,
 	}
 
+	kid, key := a.keys.SigningKey()
 	token := jwt.NewWithClaims(jwt.SigningMethodRS384, claims)
-	signedToken, err := token.SignedString(a.privateKey)
+	token.Header["kid"] = kid
+	signedToken, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
+
+// Аксесс токены не хранятся в БД, поэтому отозванные токены держатся в денайлисте
+// до истечения их срока действия:
+//
+//	jti:<jti> - отозван конкретный токен (выход из текущей сессии)
+//	user:<id> - отозваны все токены пользователя, выпущенные раньше отметки
+//	            (выход со всех устройств). отметка - UUIDv7, как и jti, поэтому порядок
+//	            выпуска и отзыва определяется точнее секунды iat: токены, выданные
+//	            сразу после отзыва, действительны, а выданные до него - нет
+func denylistTokenKey(tokenID string) string {
+	return "jti:" + tokenID
+}
+
+func denylistUserKey(userID string) string {
+	return "user:" + userID
+}
+
+func (a *App) revokeAccessToken(u models.UserToken) error {
+	ttl := time.Until(u.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	// кэш округляет ttl до секунд вниз, запись не должна пропасть раньше токена
+	return a.denylist.AddWithTTL(denylistTokenKey(u.TokenID), u.ID, ttl+time.Second)
+}
+
+func (a *App) revokeUserAccessTokens(userID string) error {
+	mark, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	return a.denylist.AddWithTTL(denylistUserKey(userID), mark.String(), a.accessTTL+time.Second)
+}
+
+func (a *App) isAccessTokenRevoked(u models.UserToken) bool {
+	if _, ok := a.denylist.Get(denylistTokenKey(u.TokenID)); ok {
+		return true
+	}
+	v, ok := a.denylist.Get(denylistUserKey(u.ID))
+	if !ok {
+		return false
+	}
+	mark, err := uuid.Parse(v)
+	if err != nil {
+		return true
+	}
+	return issuedBefore(u.TokenID, mark)
+}
+
+// UUIDv7 упорядочены по времени создания побайтово. jti других версий
+// (токены, выпущенные до перехода на v7) считаются выпущенными раньше отметки
+func issuedBefore(tokenID string, mark uuid.UUID) bool {
+	jti, err := uuid.Parse(tokenID)
+	if err != nil || jti.Version() != 7 {
+		return true
+	}
+	return bytes.Compare(jti[:], mark[:]) < 0
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/google/uuid"
+)
+
+// UUIDv7 с заданными миллисекундами и счетчиком в rand_a, как у uuid.NewV7
+func testV7(ms int64, seq uint16) uuid.UUID {
+	var u uuid.UUID
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(ms))
+	copy(u[:6], ts[2:])
+	binary.BigEndian.PutUint16(u[6:8], seq&0x0fff|0x7000)
+	u[8] = 0x80
+	return u
+}
+
+func TestIssuedBefore(t *testing.T) {
+	ms := time.Date(2025, 11, 12, 12, 0, 0, 0, time.UTC).UnixMilli()
+	mark := testV7(ms, 10)
+	tests := []struct {
+		name string
+		jti  string
+		want bool
+	}{
+		{"earlier second", testV7(ms-1000, 10).String(), true},
+		{"same second, earlier ms", testV7(ms-1, 4000).String(), true},
+		{"same ms, earlier counter", testV7(ms, 9).String(), true},
+		{"same id", mark.String(), false},
+		{"same ms, later counter", testV7(ms, 11).String(), false},
+		{"same second, later ms", testV7(ms+1, 0).String(), false},
+		{"v4 jti", uuid.New().String(), true},
+		{"malformed jti", "jti", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issuedBefore(tt.jti, mark); got != tt.want {
+				t.Errorf("issuedBefore() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	a, repo, _ := newTestApp(t)
+	repo.addUser("u1", "Ivan", "ivan@example.com")
+	u := models.UserToken{ID: "u1", Name: "Ivan"}
+	ctx := logger.WithUserID(context.Background(), "u1")
+
+	// iat в секундах, поэтому проверяется именно случай одной секунды: токен до отзыва
+	// и токен после него. на границе секунды попытка повторяется
+	for range 5 {
+		before, err := a.CreateAccessToken(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.LogoutAll(ctx); err != nil {
+			t.Fatal(err)
+		}
+		after, err := a.CreateAccessToken(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb, err := a.parseToken(before, TokenTypeAccess, AccessAudience)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ta, err := a.parseToken(after, TokenTypeAccess, AccessAudience)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tb.IssuedAt.Equal(ta.IssuedAt.Time) {
+			continue
+		}
+
+		if _, err = a.ParseAccessToken(before); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("token issued before LogoutAll: %v, want ErrTokenRevoked", err)
+		}
+		if _, err = a.ParseAccessToken(after); err != nil {
+			t.Errorf("token issued after LogoutAll: %v", err)
+		}
+		// другие пользователи не затронуты
+		other, err := a.CreateAccessToken(models.UserToken{ID: "u2", Name: "Petr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = a.ParseAccessToken(other); err != nil {
+			t.Errorf("other user's token: %v", err)
+		}
+		return
+	}
+	t.Fatal("tokens were never issued within one second")
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	a, _, _ := newTestApp(t)
+	u := models.UserToken{ID: "u1", Name: "Ivan"}
+	first, err := a.CreateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.CreateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := a.ParseAccessToken(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.revokeAccessToken(parsed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.ParseAccessToken(first); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("revoked token: %v, want ErrTokenRevoked", err)
+	}
+	// выход из одной сессии не задевает другие
+	if _, err = a.ParseAccessToken(second); err != nil {
+		t.Errorf("token of another session: %v", err)
+	}
+}
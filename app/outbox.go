@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/glekoz/online-shop_user/metrics"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Очередь писем. Письма ставятся в очередь в БД, а воркеры отправляют их
+// с удваивающейся паузой между попытками. После outboxMaxAttempts неудач
+// письмо помечается мертвым и остается в таблице для разбора.
+// Паузы между попытками (10с, 20с, ... 10м) в сумме около 20 минут, вместе с
+// таймаутами отправки - до 22 минут. Это меньше времени жизни токенов в письмах
+// (cache.mail_token_ttl, по умолчанию час), иначе ссылка из доставленного письма
+// уже не работала бы
+const (
+	outboxBatchSize      = 50
+	outboxLease          = 2 * time.Minute  // больше outboxSendTimeout, чтобы письмо не забрали дважды
+	outboxSendTimeout    = 15 * time.Second // меньше grpc.drain_timeout по умолчанию, чтобы начатая отправка успевала закончиться при остановке
+	outboxReleaseTimeout = 5 * time.Second
+	outboxBackoffBase    = 10 * time.Second
+	outboxBackoffMax     = 10 * time.Minute
+	outboxMaxAttempts    = 8
+	outboxRetention      = 7 * 24 * time.Hour // отправленные письма старше этого удаляются
+
+	outboxStatsEvery   = time.Minute
+	outboxCleanupEvery = time.Hour
+)
+
+// RunMailOutbox отправляет письма из очереди в workers горутин, проверяя ее раз в every.
+// Работает, пока не отменен ctx. При остановке доводятся до конца только начатые
+// отправки, остальные забранные письма возвращаются в очередь для других реплик
+// или следующего запуска
+func (a *App) RunMailOutbox(ctx context.Context, workers int, every time.Duration) {
+	jobs := make(chan models.OutboxMail)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				a.deliverMail(ctx, m)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	stats := time.NewTicker(outboxStatsEvery)
+	defer stats.Stop()
+	cleanup := time.NewTicker(outboxCleanupEvery)
+	defer cleanup.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stats.C:
+			a.reportOutbox(ctx)
+			continue
+		case <-cleanup.C:
+			a.cleanupOutbox(ctx)
+			continue
+		case <-ticker.C:
+		}
+		// очередь выбирается до конца, чтобы всплеск регистраций не ждал следующих тиков
+		for {
+			mails, err := a.Repo.ClaimMails(ctx, outboxBatchSize, outboxLease)
+			if err != nil {
+				a.logger.ErrorContext(ctx, "mail claiming failed", "error", err.Error())
+				break
+			}
+			for i, m := range mails {
+				select {
+				case jobs <- m:
+				case <-ctx.Done():
+					a.releaseMails(ctx, mails[i:])
+					return
+				}
+			}
+			if len(mails) < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (a *App) deliverMail(ctx context.Context, m models.OutboxMail) {
+	ctx = logger.WithDetails(ctx, "outbox id", m.ID)
+	ctx = logger.WithDetails(ctx, "template", m.Template)
+	// отмена ctx не прерывает отправку, иначе письмо могло бы уйти, не будучи отмеченным
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outboxSendTimeout)
+	defer cancel()
+	// своя трасса на каждую попытку, запросы к БД в ней тоже записываются
+	ctx, span := tracing.Start(ctx, "MailOutbox.deliver", trace.WithNewRoot(), trace.WithAttributes(
+		attribute.Int64("outbox.id", m.ID),
+		attribute.Int("outbox.attempt", m.Attempts),
+	))
+	defer span.End()
+
+	msgID, err := a.Mail.Deliver(ctx, m)
+	if err == nil {
+		if err = a.Repo.MarkMailSent(ctx, m.ID, msgID); err != nil {
+			a.logger.ErrorContext(ctx, "mail marking failed", "error", err.Error())
+		}
+		a.metrics.MailDelivery(metrics.MailSent)
+		a.logger.InfoContext(ctx, "email sent", "msgID", msgID, "attempt", m.Attempts)
+		return
+	}
+
+	reason := err.Error()
+	if m.Attempts >= outboxMaxAttempts {
+		if err = a.Repo.MarkMailDead(ctx, m.ID, reason); err != nil {
+			a.logger.ErrorContext(ctx, "mail marking failed", "error", err.Error())
+		}
+		a.metrics.MailDelivery(metrics.MailDead)
+		a.logger.ErrorContext(ctx, "email dead-lettered", "error", reason, "attempt", m.Attempts)
+		return
+	}
+	next := time.Now().Add(outboxBackoff(m.Attempts))
+	if err = a.Repo.MarkMailFailed(ctx, m.ID, next, reason); err != nil {
+		a.logger.ErrorContext(ctx, "mail marking failed", "error", err.Error())
+	}
+	a.metrics.MailDelivery(metrics.MailFailed)
+	a.logger.WarnContext(ctx, "mail malfunction, will retry", "error", reason, "attempt", m.Attempts, "next attempt", next)
+}
+
+// без этого письма ждали бы окончания аренды, а попытка считалась бы потраченной
+func (a *App) releaseMails(ctx context.Context, mails []models.OutboxMail) {
+	ids := make([]int64, 0, len(mails))
+	for _, m := range mails {
+		ids = append(ids, m.ID)
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outboxReleaseTimeout)
+	defer cancel()
+	if err := a.Repo.ReleaseMails(ctx, ids); err != nil {
+		a.logger.ErrorContext(ctx, "mail releasing failed", "error", err.Error(), "count", len(ids))
+	}
+}
+
+// пауза после n-й неудачной попытки
+func outboxBackoff(n int) time.Duration {
+	shift := min(n-1, 20)
+	return min(outboxBackoffBase<<shift, outboxBackoffMax)
+}
+
+func (a *App) MailOutboxStats(ctx context.Context) (models.OutboxStats, error) {
+	return a.Repo.GetOutboxStats(ctx)
+}
+
+func (a *App) reportOutbox(ctx context.Context) {
+	s, err := a.MailOutboxStats(ctx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "outbox stats fetching failed", "error", err.Error())
+		return
+	}
+	if s.Pending == 0 && s.Dead == 0 {
+		return
+	}
+	a.logger.InfoContext(ctx, "mail outbox",
+		"pending", s.Pending, "retrying", s.Retrying, "dead", s.Dead, "oldest pending", s.OldestPending.Round(time.Second))
+}
+
+// истекшие токены из писем удаляются здесь же: новый токен с тем же ключом
+// заменяет истекший, но ключи, которые больше не используются, остались бы навсегда
+func (a *App) cleanupOutbox(ctx context.Context) {
+	n, err := a.Repo.DeleteSentMails(ctx, time.Now().Add(-outboxRetention))
+	if err != nil {
+		a.logger.ErrorContext(ctx, "sent mails deletion failed", "error", err.Error())
+	} else if n > 0 {
+		a.logger.InfoContext(ctx, "sent mails deleted", "count", n)
+	}
+	n, err = a.Repo.DeleteExpiredMailTokens(ctx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "expired mail tokens deletion failed", "error", err.Error())
+	} else if n > 0 {
+		a.logger.InfoContext(ctx, "expired mail tokens deleted", "count", n)
+	}
+}
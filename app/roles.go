@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+)
+
+// Authorize проверяет, что у отправителя запроса есть право permission.
+// Права берутся из БД, а не из токена, чтобы разжалованный пользователь
+// терял их сразу, а не после истечения аксесс токена
+func (a *App) Authorize(ctx context.Context, permission string) error {
+	ctx, span := tracing.Start(ctx, "App.Authorize")
+	defer span.End()
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return ErrNoRUID
+	}
+	ok, err := a.Repo.HasPermission(ctx, RUID, permission)
+	if err != nil {
+		return logger.WrapError(logger.WithDetails(ctx, "permission", permission), err)
+	}
+	if !ok {
+		return logger.WrapError(logger.WithDetails(ctx, "permission", permission), ErrForbidden)
+	}
+	return nil
+}
+
+// вместе с ролью выдаются младшие: админ становится и модератором
+func (a *App) PromoteModer(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.PromoteModer")
+	defer span.End()
+	return a.grantRole(ctx, userID, models.RoleModer, models.PermModersManage)
+}
+
+func (a *App) PromoteAdmin(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.PromoteAdmin")
+	defer span.End()
+	return a.grantRole(ctx, userID, models.RoleAdmin, models.PermAdminsManage)
+}
+
+// специально разнесен с PromoteAdmin
+func (a *App) PromoteCoreAdmin(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.PromoteCoreAdmin")
+	defer span.End()
+	return a.grantRole(ctx, userID, models.RoleCoreAdmin, models.PermCoreAdminsManage)
+}
+
+// разжаловать модератора может любой админ, но у админа права модератора
+// забрать нельзя - сначала надо снять права админа
+func (a *App) DemoteModer(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.DemoteModer")
+	defer span.End()
+	return a.revokeRole(ctx, userID, models.RoleModer, models.PermModersManage)
+}
+
+// права модератора остаются
+func (a *App) DemoteAdmin(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.DemoteAdmin")
+	defer span.End()
+	return a.revokeRole(ctx, userID, models.RoleAdmin, models.PermAdminsManage)
+}
+
+// специально разнесен с DemoteAdmin, как и при повышении.
+// последний core админ защищен в репозитории
+func (a *App) DemoteCoreAdmin(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.DemoteCoreAdmin")
+	defer span.End()
+	return a.revokeRole(ctx, userID, models.RoleCoreAdmin, models.PermCoreAdminsManage)
+}
+
+// новые права попадут в токен при следующем обновлении по рефреш токену
+func (a *App) grantRole(ctx context.Context, userID, role, permission string) error {
+	if err := a.Authorize(ctx, permission); err != nil {
+		return err
+	}
+	RUID, _ := getRUID(ctx)
+	err := a.Repo.GrantRole(ctx, userID, role, RUID)
+	if err != nil {
+		ctx = logger.WithDetails(ctx, "id", userID)
+		ctx = logger.WithDetails(ctx, "role", role)
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return logger.WrapError(ctx, ErrRoleAlreadyGranted)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	return nil
+}
+
+// себя разжаловать нельзя, чтобы админ случайно не остался без прав
+func (a *App) revokeRole(ctx context.Context, userID, role, permission string) error {
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return ErrNoRUID
+	}
+	if RUID == userID {
+		return ErrSelfDemotion
+	}
+	if err = a.Authorize(ctx, permission); err != nil {
+		return err
+	}
+	err = a.Repo.RevokeRole(ctx, userID, role, RUID)
+	if err != nil {
+		ctx = logger.WithDetails(ctx, "id", userID)
+		ctx = logger.WithDetails(ctx, "role", role)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return logger.WrapError(ctx, ErrUserNotFound)
+		case errors.Is(err, repository.ErrHasHigherRole):
+			return logger.WrapError(ctx, ErrHasHigherRole)
+		case errors.Is(err, repository.ErrLastCoreAdmin):
+			return logger.WrapError(ctx, ErrLastCoreAdmin)
+		default:
+			return logger.WrapError(ctx, err)
+		}
+	}
+	// права зашиты в аксесс токены, поэтому они отзываются, и по рефреш токену
+	// пользователь получит новые уже без снятых прав
+	err = a.revokeUserAccessTokens(userID)
+	if err != nil {
+		ctx = logger.WithDetails(ctx, "id", userID)
+		return logger.WrapError(ctx, err)
+	}
+	return nil
+}
+
+// бан не мешает входу: ограничения применяют другие сервисы, узнавая о нем
+// через CheckPermissions. забанить пользователя с ролью может только тот,
+// кто может эту роль снять
+func (a *App) BanUser(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.BanUser")
+	defer span.End()
+	return a.setBanned(ctx, userID, true)
+}
+
+func (a *App) UnbanUser(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "App.UnbanUser")
+	defer span.End()
+	return a.setBanned(ctx, userID, false)
+}
+
+// право, нужное для управления обладателем каждой роли
+var roleManagePermissions = map[string]string{
+	models.RoleModer:     models.PermModersManage,
+	models.RoleAdmin:     models.PermAdminsManage,
+	models.RoleCoreAdmin: models.PermCoreAdminsManage,
+}
+
+func (a *App) setBanned(ctx context.Context, userID string, banned bool) error {
+	RUID, err := getRUID(ctx)
+	if err != nil {
+		return ErrNoRUID
+	}
+	if RUID == userID {
+		return ErrSelfBan
+	}
+	if err = a.Authorize(ctx, models.PermUsersBan); err != nil {
+		return err
+	}
+	ctx = logger.WithDetails(ctx, "id", userID)
+	roles, err := a.Repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	for _, role := range roles {
+		if err = a.Authorize(ctx, roleManagePermissions[role]); err != nil {
+			return err
+		}
+	}
+	err = a.Repo.SetBanned(ctx, userID, banned)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	return nil
+}
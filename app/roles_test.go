@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+// права ролей как в миграции roles, младшие роли хранятся у пользователя явно
+var testRolePermissions = map[string][]string{
+	models.RoleModer:     {models.PermCommentsDelete},
+	models.RoleAdmin:     {models.PermUsersRead, models.PermUsersDelete, models.PermUsersBan, models.PermModersManage, models.PermAuditRead},
+	models.RoleCoreAdmin: {models.PermAdminsManage, models.PermCoreAdminsManage},
+}
+
+func (r *fakeRepo) addUserWithRole(id, role string) *fakeUser {
+	u := r.addUser(id, id, id+"@example.com")
+	u.roles = append(models.LowerRoles(role), role)
+	return u
+}
+
+func (r *fakeRepo) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(userID)
+	if u == nil {
+		return false, nil
+	}
+	for _, role := range u.roles {
+		if slices.Contains(testRolePermissions[role], permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(userID)
+	if u == nil {
+		return nil, nil
+	}
+	return slices.Clone(u.roles), nil
+}
+
+// проверки как в Repository.RevokeRole
+func (r *fakeRepo) RevokeRole(ctx context.Context, userID, role, actorID string) error {
+	if f := r.beforeRevoke; f != nil {
+		r.beforeRevoke = nil
+		f()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.userByID(userID)
+	if u == nil || !slices.Contains(u.roles, role) {
+		return repository.ErrNotFound
+	}
+	for _, higher := range models.HigherRoles(role) {
+		if slices.Contains(u.roles, higher) {
+			return repository.ErrHasHigherRole
+		}
+	}
+	if role == models.RoleCoreAdmin {
+		cores := 0
+		for _, other := range r.users {
+			if !other.deleted && slices.Contains(other.roles, models.RoleCoreAdmin) {
+				cores++
+			}
+		}
+		if cores <= 1 {
+			return repository.ErrLastCoreAdmin
+		}
+	}
+	u.roles = slices.DeleteFunc(u.roles, func(r string) bool { return r == role })
+	return nil
+}
+
+func asUser(id string) context.Context {
+	return logger.WithUserID(context.Background(), id)
+}
+
+func TestRevokeRole(t *testing.T) {
+	t.Run("self demotion", func(t *testing.T) {
+		a, repo, _ := newTestApp(t)
+		repo.addUserWithRole("core1", models.RoleCoreAdmin)
+		repo.addUserWithRole("core2", models.RoleCoreAdmin)
+		if err := a.DemoteCoreAdmin(asUser("core1"), "core1"); !errors.Is(err, ErrSelfDemotion) {
+			t.Fatalf("%v, want ErrSelfDemotion", err)
+		}
+		if !slices.Contains(repo.userByID("core1").roles, models.RoleCoreAdmin) {
+			t.Error("role is revoked")
+		}
+	})
+
+	t.Run("last core admin", func(t *testing.T) {
+		a, repo, _ := newTestApp(t)
+		repo.addUserWithRole("core1", models.RoleCoreAdmin)
+		repo.addUserWithRole("core2", models.RoleCoreAdmin)
+		// core админы разжалуют друг друга одновременно: оба прошли проверку прав,
+		// но второй запрос доходит до БД, когда core админ остался один
+		repo.beforeRevoke = func() {
+			if err := a.DemoteCoreAdmin(asUser("core2"), "core1"); err != nil {
+				t.Errorf("first demotion: %v", err)
+			}
+		}
+		if err := a.DemoteCoreAdmin(asUser("core1"), "core2"); !errors.Is(err, ErrLastCoreAdmin) {
+			t.Fatalf("%v, want ErrLastCoreAdmin", err)
+		}
+		if !slices.Contains(repo.userByID("core2").roles, models.RoleCoreAdmin) {
+			t.Error("the last core admin is demoted")
+		}
+	})
+
+	t.Run("demoted core admin loses permission", func(t *testing.T) {
+		a, repo, _ := newTestApp(t)
+		repo.addUserWithRole("core1", models.RoleCoreAdmin)
+		repo.addUserWithRole("core2", models.RoleCoreAdmin)
+		if err := a.DemoteCoreAdmin(asUser("core1"), "core2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.DemoteCoreAdmin(asUser("core2"), "core1"); !errors.Is(err, ErrForbidden) {
+			t.Fatalf("%v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("lower role under higher", func(t *testing.T) {
+		a, repo, _ := newTestApp(t)
+		repo.addUserWithRole("core1", models.RoleCoreAdmin)
+		repo.addUserWithRole("admin1", models.RoleAdmin)
+		if err := a.DemoteModer(asUser("core1"), "admin1"); !errors.Is(err, ErrHasHigherRole) {
+			t.Fatalf("%v, want ErrHasHigherRole", err)
+		}
+	})
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  string
+		target string
+		want   error
+	}{
+		{"core admin is protected", "core1", "core2", ErrForbidden},
+		{"admin can't delete admin", "admin1", "admin2", ErrForbidden},
+		{"core admin deletes admin", "core1", "admin1", nil},
+		{"admin deletes user", "admin1", "user1", nil},
+		{"moder can't delete user", "moder1", "user1", ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, _ := newTestApp(t)
+			repo.addUserWithRole("core1", models.RoleCoreAdmin)
+			repo.addUserWithRole("core2", models.RoleCoreAdmin)
+			repo.addUserWithRole("admin1", models.RoleAdmin)
+			repo.addUserWithRole("admin2", models.RoleAdmin)
+			repo.addUserWithRole("moder1", models.RoleModer)
+			repo.addUser("user1", "user1", "user1@example.com")
+
+			err := a.AdminDeleteUser(asUser(tt.actor), tt.target)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("%v, want %v", err, tt.want)
+			}
+			deleted := repo.userByID(tt.target) == nil
+			if deleted != (tt.want == nil) {
+				t.Errorf("deleted = %t", deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glekoz/online-shop_user/mail"
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+)
+
+// Защита от подбора пароля. Попытки считаются отдельно для почты (с любых адресов)
+// и для пары IP + почта: первый счетчик не дает распределенно подбирать пароль
+// к одному аккаунту, второй быстрее тормозит одиночного атакующего.
+// После бесплатных попыток каждая следующая удваивает паузу до следующей
+// проверки пароля, а после accountLockThreshold неудач подряд аккаунт блокируется
+// до истечения accountLockDuration или до перехода по ссылке из письма.
+const (
+	loginFailureWindow = time.Hour // попытки старше этого забываются
+
+	ipEmailFreeAttempts = 3
+	emailFreeAttempts   = 5
+	loginBackoffBase    = time.Second
+	loginBackoffMax     = 15 * time.Minute
+
+	accountLockThreshold = 10
+	accountLockDuration  = time.Hour
+)
+
+// ошибка входа, после которой клиенту нужно подождать RetryAfter
+type RetryError struct {
+	Err        error // ErrLoginThrottled или ErrAccountLocked
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err.Error(), e.RetryAfter)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	emailLoginPolicy = models.LoginPolicy{
+		Window:        loginFailureWindow,
+		FreeAttempts:  emailFreeAttempts,
+		BackoffBase:   loginBackoffBase,
+		BackoffMax:    loginBackoffMax,
+		LockThreshold: accountLockThreshold,
+		LockDuration:  accountLockDuration,
+	}
+	ipEmailLoginPolicy = models.LoginPolicy{
+		Window:       loginFailureWindow,
+		FreeAttempts: ipEmailFreeAttempts,
+		BackoffBase:  loginBackoffBase,
+		BackoffMax:   loginBackoffMax,
+	}
+)
+
+// если счетчик не удалось записать, вход отклоняется, иначе при сбое БД
+// подбор шел бы без ограничений
+const loginStoreErrorRetry = time.Minute
+
+// засчитывает попытку до сравнения пароля: пока действует пауза, пароль не проверяется,
+// а параллельные попытки не проходят мимо счетчика, потому что проверка и увеличение -
+// один запрос. успешный вход сбрасывает счетчики.
+// locked - эта попытка заблокировала аккаунт, о чем сообщается при неверном пароле
+func (a *App) claimLoginAttempt(ctx context.Context, email string) (locked bool, err error) {
+	key, ip := loginKey(email), getIP(ctx)
+	// счетчик почты первый и отдельно: его не обойти сменой адреса
+	att, err := a.Repo.ClaimLoginAttempt(ctx, key, "", emailLoginPolicy)
+	if err != nil {
+		return false, a.loginBlocked(ctx, key, ip, err)
+	}
+	if ip != "" {
+		if _, err = a.Repo.ClaimLoginAttempt(ctx, key, ip, ipEmailLoginPolicy); err != nil {
+			return false, a.loginBlocked(ctx, key, ip, err)
+		}
+	}
+	return att.Locked, nil
+}
+
+// сколько ждать клиенту, которому не удалось засчитать попытку
+func (a *App) loginBlocked(ctx context.Context, key, ip string, err error) error {
+	if !errors.Is(err, repository.ErrLoginBlocked) {
+		a.logger.ErrorContext(ctx, "login attempt registration failed", "error", err.Error())
+		return logger.WrapError(ctx, &RetryError{Err: ErrLoginThrottled, RetryAfter: loginStoreErrorRetry})
+	}
+	fs, err := a.Repo.GetLoginFailures(ctx, key, ip)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "login failures fetching failed", "error", err.Error())
+		return logger.WrapError(ctx, &RetryError{Err: ErrLoginThrottled, RetryAfter: loginStoreErrorRetry})
+	}
+	// пауза могла закончиться между запросами, тогда хватит минимальной
+	wait := loginBackoffBase
+	locked := false
+	for _, f := range fs {
+		d := time.Until(f.BlockedUntil)
+		if d <= 0 {
+			continue
+		}
+		if f.IsLocked {
+			locked = true
+		}
+		wait = max(wait, d)
+	}
+	if locked {
+		return logger.WrapError(ctx, &RetryError{Err: ErrAccountLocked, RetryAfter: wait})
+	}
+	return logger.WrapError(ctx, &RetryError{Err: ErrLoginThrottled, RetryAfter: wait})
+}
+
+// userID пустой, если аккаунта с такой почтой нет: ответ тот же,
+// чтобы по нему нельзя было понять, зарегистрирована ли почта
+func (a *App) loginFailed(ctx context.Context, email, userID, locale string, locked bool) error {
+	if !locked {
+		return logger.WrapError(ctx, ErrInvalidCredentials)
+	}
+	if userID != "" {
+		a.audit(ctx, models.AuditAccountLocked, userID)
+		a.sendUnlockMessage(ctx, userID, email, locale)
+	}
+	return logger.WrapError(ctx, &RetryError{Err: ErrAccountLocked, RetryAfter: accountLockDuration})
+}
+
+func (a *App) resetLoginFailures(ctx context.Context, email string) {
+	err := a.Repo.ResetLoginFailures(ctx, loginKey(email), getIP(ctx))
+	if err != nil {
+		a.logger.ErrorContext(ctx, "login failures reset failed", "error", err.Error())
+	}
+}
+
+func (a *App) sendUnlockMessage(ctx context.Context, userID, email, locale string) {
+	// ссылка типа /unlock/<uid>/<token>
+	token := rand.Text()
+	link := fmt.Sprintf("%s/unlock/%s/%s", a.frontAddr, userID, token)
+	msgID, err := a.Mail.SendAccountUnlockMessage(locale, userID, email, token, link)
+	if err != nil {
+		if errors.Is(err, mail.ErrMsgAlreadySent) {
+			a.logger.InfoContext(ctx, "unlock message has already been sent")
+			return
+		}
+		a.logger.ErrorContext(ctx, "mail malfunction", "error", err.Error())
+		return
+	}
+	a.logger.InfoContext(ctx, "email queued", "outbox id", msgID)
+}
+
+// ссылка на этот метод приходит в письме о блокировке. снимается только блокировка
+// почты, пауза для адресов, с которых подбирали пароль, остается
+func (a *App) UnlockAccount(ctx context.Context, userID, unlocktoken string) error {
+	ctx, span := tracing.Start(ctx, "App.UnlockAccount")
+	defer span.End()
+	ctx = logger.WithDetails(ctx, "id", userID)
+	ok, err := a.Mail.CheckUnlockToken(userID, unlocktoken)
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	if !ok {
+		return logger.WrapError(ctx, ErrWrongMailToken)
+	}
+	ctx = logger.WithUserID(ctx, userID)
+	user, err := a.Repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return logger.WrapError(ctx, ErrUserNotFound)
+		}
+		return logger.WrapError(ctx, err)
+	}
+	err = a.Repo.ResetLoginFailures(ctx, loginKey(user.Email), "")
+	if err != nil {
+		return logger.WrapError(ctx, err)
+	}
+	a.audit(ctx, models.AuditAccountUnlocked, userID)
+	return nil
+}
+
+// удаляет счетчики, которые уже ни на что не влияют
+func (a *App) RunLoginFailuresCleanup(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := a.Repo.DeleteStaleLoginFailures(ctx, time.Now().Add(-loginFailureWindow))
+		if err != nil {
+			a.logger.ErrorContext(ctx, "stale login failures deletion failed", "error", err.Error())
+			continue
+		}
+		if n > 0 {
+			a.logger.InfoContext(ctx, "stale login failures deleted", "count", n)
+		}
+	}
+}
+
+// почта сравнивается без учета регистра, иначе счетчик обходится сменой регистра
+func loginKey(email string) string {
+	return strings.ToLower(email)
+}
@@ -0,0 +1,337 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// счетчики входа в фейке: ключ - почта и ip, как первичный ключ login_failures
+type loginKeyIP struct{ email, ip string }
+
+// как запрос ClaimLoginAttempt, без сброса по окну: тесты короче окна
+func (r *fakeRepo) ClaimLoginAttempt(ctx context.Context, email, ip string, p models.LoginPolicy) (models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loginErr != nil {
+		return models.LoginAttempt{}, r.loginErr
+	}
+	if r.failures == nil {
+		r.failures = make(map[loginKeyIP]*models.LoginFailures)
+	}
+	k := loginKeyIP{email, ip}
+	f, ok := r.failures[k]
+	if !ok {
+		f = &models.LoginFailures{Email: email, IP: ip}
+		r.failures[k] = f
+	}
+	if time.Now().Before(f.BlockedUntil) {
+		return models.LoginAttempt{}, repository.ErrLoginBlocked
+	}
+	f.Failures++
+	f.IsLocked = p.LockThreshold > 0 && f.Failures >= p.LockThreshold
+	switch {
+	case f.IsLocked:
+		f.BlockedUntil = time.Now().Add(p.LockDuration)
+	case f.Failures > p.FreeAttempts:
+		f.BlockedUntil = time.Now().Add(min(p.BackoffBase<<(f.Failures-p.FreeAttempts-1), p.BackoffMax))
+	}
+	return models.LoginAttempt{Failures: f.Failures, Locked: f.IsLocked}, nil
+}
+
+func (r *fakeRepo) GetLoginFailures(ctx context.Context, email, ip string) ([]models.LoginFailures, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failuresErr != nil {
+		return nil, r.failuresErr
+	}
+	var fs []models.LoginFailures
+	for _, k := range []loginKeyIP{{email, ""}, {email, ip}} {
+		if f, ok := r.failures[k]; ok && !slices.ContainsFunc(fs, func(x models.LoginFailures) bool { return x.IP == k.ip }) {
+			fs = append(fs, *f)
+		}
+	}
+	return fs, nil
+}
+
+func (r *fakeRepo) ResetLoginFailures(ctx context.Context, email, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, loginKeyIP{email, ""})
+	delete(r.failures, loginKeyIP{email, ip})
+	return nil
+}
+
+func (r *fakeRepo) loginFailures(email, ip string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.failures[loginKeyIP{email, ip}]; ok {
+		return f.Failures
+	}
+	return 0
+}
+
+func (r *fakeRepo) GetUserByEmail(ctx context.Context, email string) (models.UserTokenWithPassword, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email && !u.deleted {
+			return models.UserTokenWithPassword{ID: u.ID, Name: u.Name, HashedPassword: u.hash, Locale: u.Locale}, nil
+		}
+	}
+	return models.UserTokenWithPassword{}, repository.ErrNotFound
+}
+
+func (r *fakeRepo) AddAuditEntry(ctx context.Context, action, targetID string, before, after any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = append(r.audit, action)
+	return nil
+}
+
+func (m *fakeMail) SendAccountUnlockMessage(locale, userID, email string, unlocktoken, link string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.unlock == nil {
+		m.unlock = make(map[string]string)
+	}
+	m.unlock[userID] = unlocktoken
+	return "1", nil
+}
+
+func (m *fakeMail) CheckUnlockToken(userID, token string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.unlock[userID]
+	if !ok || t != token {
+		return false, nil
+	}
+	delete(m.unlock, userID)
+	return true, nil
+}
+
+const (
+	testEmail    = "ivan@example.com"
+	testPassword = "correct horse"
+	testIP       = "203.0.113.7"
+)
+
+func newLoginTestApp(t *testing.T) (*App, *fakeRepo, *fakeMail) {
+	t.Helper()
+	a, repo, mail := newTestApp(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.addUser("u1", "Ivan", testEmail).hash = string(hash)
+	return a, repo, mail
+}
+
+func withIP(ip string) context.Context {
+	return context.WithValue(context.Background(), logger.LogDataKey, logger.LogData{IPAddress: ip})
+}
+
+func TestLoginThrottle(t *testing.T) {
+	storeErr := errors.New("connection refused")
+	tests := []struct {
+		name string
+		ip   string
+		// неверные пароли до проверяемой попытки
+		failures int
+		setup    func(repo *fakeRepo)
+		password string
+		want     error
+		// 0 - ошибка не RetryError
+		minRetry, maxRetry time.Duration
+	}{
+		{
+			name: "free attempts", ip: testIP, failures: ipEmailFreeAttempts - 1,
+			password: "wrong", want: ErrInvalidCredentials,
+		},
+		{
+			// первая попытка сверх бесплатных ставит паузу, следующая до ее конца не проверяет пароль
+			name: "ip backoff", ip: testIP, failures: ipEmailFreeAttempts + 1,
+			password: testPassword, want: ErrLoginThrottled,
+			minRetry: time.Millisecond, maxRetry: loginBackoffBase,
+		},
+		{
+			name: "email backoff without ip", failures: emailFreeAttempts + 1,
+			password: testPassword, want: ErrLoginThrottled,
+			minRetry: time.Millisecond, maxRetry: loginBackoffBase,
+		},
+		{
+			name: "lockout", failures: accountLockThreshold - 1,
+			setup: func(repo *fakeRepo) {
+				// паузы между попытками уже прошли
+				repo.failures[loginKeyIP{testEmail, ""}].BlockedUntil = time.Time{}
+			},
+			password: "wrong", want: ErrAccountLocked,
+			minRetry: accountLockDuration, maxRetry: accountLockDuration,
+		},
+		{
+			name: "locked account rejects correct password", failures: accountLockThreshold,
+			password: testPassword, want: ErrAccountLocked,
+			minRetry: accountLockDuration - time.Minute, maxRetry: accountLockDuration,
+		},
+		{
+			name: "claim fails closed", ip: testIP,
+			setup:    func(repo *fakeRepo) { repo.loginErr = storeErr },
+			password: testPassword, want: ErrLoginThrottled,
+			minRetry: loginStoreErrorRetry, maxRetry: loginStoreErrorRetry,
+		},
+		{
+			name: "failures fetch fails closed", ip: testIP, failures: ipEmailFreeAttempts + 1,
+			setup:    func(repo *fakeRepo) { repo.failuresErr = storeErr },
+			password: testPassword, want: ErrLoginThrottled,
+			minRetry: loginStoreErrorRetry, maxRetry: loginStoreErrorRetry,
+		},
+		{
+			name: "unknown email", failures: 0,
+			setup:    func(repo *fakeRepo) { repo.users = nil },
+			password: testPassword, want: ErrInvalidCredentials,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, _ := newLoginTestApp(t)
+			ctx := withIP(tt.ip)
+			for i := range tt.failures {
+				_, _, err := a.Login(ctx, testEmail, "wrong")
+				// паузы в фейке не ждут: пароль проверяется, пока попытка засчитывается
+				if err != nil && !errors.Is(err, ErrInvalidCredentials) && !errors.Is(err, ErrLoginThrottled) && !errors.Is(err, ErrAccountLocked) {
+					t.Fatalf("failure %d: %v", i+1, err)
+				}
+				// чтобы дойти до нужного числа неудач, прошедшие паузы сбрасываются,
+				// кроме последней
+				if i < tt.failures-1 {
+					repo.mu.Lock()
+					for _, f := range repo.failures {
+						if !f.IsLocked {
+							f.BlockedUntil = time.Time{}
+						}
+					}
+					repo.mu.Unlock()
+				}
+			}
+			if tt.setup != nil {
+				tt.setup(repo)
+			}
+
+			_, _, err := a.Login(ctx, testEmail, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("%v, want %v", err, tt.want)
+			}
+			var re *RetryError
+			if !errors.As(err, &re) {
+				if tt.maxRetry != 0 {
+					t.Fatalf("%v is not a RetryError", err)
+				}
+				return
+			}
+			if tt.maxRetry == 0 {
+				t.Fatalf("unexpected retry after %s", re.RetryAfter)
+			}
+			if re.RetryAfter < tt.minRetry || re.RetryAfter > tt.maxRetry {
+				t.Errorf("RetryAfter = %s, want within [%s, %s]", re.RetryAfter, tt.minRetry, tt.maxRetry)
+			}
+		})
+	}
+}
+
+func TestLoginResetsFailures(t *testing.T) {
+	a, repo, _ := newLoginTestApp(t)
+	ctx := withIP(testIP)
+	if _, _, err := a.Login(ctx, testEmail, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatal(err)
+	}
+	if _, _, err := a.Login(ctx, testEmail, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if n := repo.loginFailures(testEmail, ""); n != 0 {
+		t.Errorf("email counter = %d after successful login", n)
+	}
+	if n := repo.loginFailures(testEmail, testIP); n != 0 {
+		t.Errorf("ip counter = %d after successful login", n)
+	}
+}
+
+func TestUnlockAccount(t *testing.T) {
+	// блокирует аккаунт и возвращает токен из письма
+	lock := func(t *testing.T, a *App, repo *fakeRepo, mail *fakeMail) string {
+		t.Helper()
+		ctx := withIP(testIP)
+		for range accountLockThreshold {
+			repo.mu.Lock()
+			for _, f := range repo.failures {
+				f.BlockedUntil = time.Time{}
+			}
+			repo.mu.Unlock()
+			a.Login(ctx, testEmail, "wrong")
+		}
+		if _, _, err := a.Login(ctx, testEmail, testPassword); !errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("account is not locked: %v", err)
+		}
+		if !slices.Contains(repo.audit, models.AuditAccountLocked) {
+			t.Error("lock is not audited")
+		}
+		token, ok := mail.unlock["u1"]
+		if !ok {
+			t.Fatal("unlock message is not sent")
+		}
+		return token
+	}
+
+	tests := []struct {
+		name   string
+		token  func(sent string) string
+		before func(repo *fakeRepo)
+		want   error
+	}{
+		{"valid token", func(sent string) string { return sent }, nil, nil},
+		{"wrong token", func(string) string { return "wrong" }, nil, ErrWrongMailToken},
+		{"deleted user", func(sent string) string { return sent }, func(repo *fakeRepo) { repo.users[0].deleted = true }, ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, mail := newLoginTestApp(t)
+			sent := lock(t, a, repo, mail)
+			if tt.before != nil {
+				tt.before(repo)
+			}
+			err := a.UnlockAccount(context.Background(), "u1", tt.token(sent))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("%v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if n := repo.loginFailures(testEmail, ""); n < accountLockThreshold {
+					t.Errorf("email counter = %d, account is unlocked", n)
+				}
+				return
+			}
+			if n := repo.loginFailures(testEmail, ""); n != 0 {
+				t.Errorf("email counter = %d after unlock", n)
+			}
+			// пауза для адреса подбора остается
+			if n := repo.loginFailures(testEmail, testIP); n == 0 {
+				t.Error("ip counter is reset")
+			}
+			if !slices.Contains(repo.audit, models.AuditAccountUnlocked) {
+				t.Error("unlock is not audited")
+			}
+			if _, _, err = a.Login(context.Background(), testEmail, testPassword); err != nil {
+				t.Errorf("login after unlock: %v", err)
+			}
+			// токен одноразовый
+			if err = a.UnlockAccount(context.Background(), "u1", sent); !errors.Is(err, ErrWrongMailToken) {
+				t.Errorf("reused token: %v, want ErrWrongMailToken", err)
+			}
+		})
+	}
+}
@@ -6,30 +6,27 @@ import (
 	"github.com/glekoz/cache"
 )
 
+// хранит отозванные аксесс токены, см. app.DenylistAPI
 type Cache struct {
-	c   *cache.Cache[string, string]
-	ttl time.Duration
+	c *cache.Cache[string, string]
 }
 
-func New(ttl int) (*Cache, error) {
+func New() (*Cache, error) {
 	c, err := cache.New[string, string]()
 	if err != nil {
 		return nil, err
 	}
-	return &Cache{
-		c:   c,
-		ttl: time.Duration(ttl) * time.Second,
-	}, nil
+	return &Cache{c: c}, nil
 }
 
-func (c *Cache) Add(userID string, mailtoken string) error {
-	return c.c.Add(userID, mailtoken, c.ttl)
+func (c *Cache) AddWithTTL(key, value string, ttl time.Duration) error {
+	return c.c.Add(key, value, ttl)
 }
 
-func (c *Cache) Get(userID string) (string, bool) {
-	return c.c.Get(userID)
+func (c *Cache) Get(key string) (string, bool) {
+	return c.c.Get(key)
 }
 
-func (c *Cache) Delete(userID string) {
-	c.c.Delete(userID)
+func (c *Cache) Delete(key string) {
+	c.c.Delete(key)
 }
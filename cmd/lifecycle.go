@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runner запускает компоненты сервиса и останавливает их по SIGINT/SIGTERM
+// или при падении любого из них. Порядок остановки:
+//
+//  1. отменяется контекст фоновых задач и вызываются stop процессов (GracefulStop сервера)
+//  2. runner ждет завершения всех процессов
+//  3. в обратном порядке вызываются закрытия из onClose (ограничитель, пул БД, трассировка, логгер)
+//
+// все шаги вместе ограничены drain
+type runner struct {
+	logger *slog.Logger
+	drain  time.Duration
+
+	procs   []proc
+	closers []closer
+}
+
+type proc struct {
+	name string
+	run  func(ctx context.Context) error
+	stop func(ctx context.Context) error // nil - процесс останавливается отменой ctx
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+func newRunner(logger *slog.Logger, drain time.Duration) *runner {
+	return &runner{logger: logger, drain: drain}
+}
+
+// процесс, работающий до остановки сервиса. завершение с ошибкой останавливает весь сервис
+func (r *runner) goProc(name string, run, stop func(ctx context.Context) error) {
+	r.procs = append(r.procs, proc{name: name, run: run, stop: stop})
+}
+
+// закрытия вызываются после остановки всех процессов в порядке, обратном регистрации
+func (r *runner) onClose(name string, close func(ctx context.Context) error) {
+	r.closers = append(r.closers, closer{name: name, close: close})
+}
+
+// закрывает все зарегистрированное, для ошибок при запуске
+func (r *runner) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.drain)
+	defer cancel()
+	return r.closeAll(ctx)
+}
+
+func (r *runner) closeAll(ctx context.Context) error {
+	var errs []error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		c := r.closers[i]
+		if err := c.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s close: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run блокируется до сигнала или падения процесса, ошибка - причина или сбой при остановке
+func (r *runner) run() error {
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	ctx, cancel := context.WithCancel(sigCtx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	failed := make(chan error, len(r.procs))
+	for _, p := range r.procs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.run(ctx)
+			if err != nil && ctx.Err() == nil {
+				failed <- fmt.Errorf("%s: %w", p.name, err)
+			}
+		}()
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		r.logger.Info("shutdown signal received")
+	case cause = <-failed:
+		r.logger.Error("component failed, shutting down", "error", cause.Error())
+	}
+	// обработка сигналов возвращается системе: если остановка зависла,
+	// повторный сигнал завершит процесс сразу
+	stopSignals()
+	cancel()
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), r.drain)
+	defer cancelDrain()
+	errs := []error{cause}
+	for _, p := range r.procs {
+		if p.stop == nil {
+			continue
+		}
+		if err := p.stop(drainCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s stop: %w", p.name, err))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-drainCtx.Done():
+		errs = append(errs, errors.New("drain deadline exceeded, some components are still running"))
+	}
+
+	errs = append(errs, r.closeAll(drainCtx))
+	err := errors.Join(errs...)
+	if err == nil {
+		r.logger.Info("shutdown complete")
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Default без транспорта почты не проходит проверку намеренно
+func validConfig() Config {
+	cfg := Default()
+	cfg.Mail.Transport = "stdout"
+	return cfg
+}
+
+func TestApplyEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("USER_SERVICE_GRPC_PORT", "9090")
+	t.Setenv("USER_SERVICE_GRPC_DRAIN_TIMEOUT", "45s")
+	t.Setenv("USER_SERVICE_DATABASE_DSN", "postgres://env")
+	t.Setenv("USER_SERVICE_DATABASE_MAX_CONNS", "7")
+	t.Setenv("USER_SERVICE_MAIL_SMTP_HOST", "smtp.example.com")
+	t.Setenv("USER_SERVICE_MAIL_SMTP_STARTTLS", "true")
+	t.Setenv("USER_SERVICE_MAIL_SMTP_PASSWORD_FILE", secret)
+	t.Setenv("USER_SERVICE_RATE_LIMIT_DEFAULT_RATE", "0.5")
+	t.Setenv("USER_SERVICE_RATE_LIMIT_ALLOWLIST", "10.0.0.0/8, ,192.168.0.0/16")
+	t.Setenv("USER_SERVICE_TRACING_SAMPLE_RATIO", "0.25")
+
+	cfg := Default()
+	if err := applyEnv(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GRPC.Port != 9090 {
+		t.Errorf("grpc.port = %d", cfg.GRPC.Port)
+	}
+	if cfg.GRPC.DrainTimeout != 45*time.Second {
+		t.Errorf("grpc.drain_timeout = %s", cfg.GRPC.DrainTimeout)
+	}
+	if cfg.Database.DSN != "postgres://env" {
+		t.Errorf("database.dsn = %q", string(cfg.Database.DSN))
+	}
+	if cfg.Database.MaxConns != 7 {
+		t.Errorf("database.max_conns = %d", cfg.Database.MaxConns)
+	}
+	if cfg.Mail.SMTP.Host != "smtp.example.com" || !cfg.Mail.SMTP.StartTLS {
+		t.Errorf("mail.smtp = %+v", cfg.Mail.SMTP)
+	}
+	if cfg.Mail.SMTP.Password != "from file" {
+		t.Errorf("mail.smtp.password from file = %q", string(cfg.Mail.SMTP.Password))
+	}
+	if cfg.RateLimit.Default.Rate != 0.5 {
+		t.Errorf("rate_limit.default.rate = %g", cfg.RateLimit.Default.Rate)
+	}
+	if want := []string{"10.0.0.0/8", "192.168.0.0/16"}; !slices.Equal(cfg.RateLimit.Allowlist, want) {
+		t.Errorf("rate_limit.allowlist = %v, want %v", cfg.RateLimit.Allowlist, want)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("tracing.sample_ratio = %g", cfg.Tracing.SampleRatio)
+	}
+	// не заданные переменные не трогают значения
+	if cfg.Keys.Dir != Default().Keys.Dir {
+		t.Errorf("keys.dir = %q", cfg.Keys.Dir)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	tests := []struct {
+		env, value string
+	}{
+		{"USER_SERVICE_GRPC_PORT", "http"},
+		{"USER_SERVICE_GRPC_DRAIN_TIMEOUT", "20"},
+		{"USER_SERVICE_MAIL_SMTP_STARTTLS", "maybe"},
+		{"USER_SERVICE_DATABASE_MAX_CONNS", "99999999999"},
+		{"USER_SERVICE_RATE_LIMIT_METHODS", "/User/Login=1"},
+		{"USER_SERVICE_DATABASE_DSN_FILE", "/nonexistent/dsn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			cfg := Default()
+			err := applyEnv(&cfg)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			// в ошибке имя переменной, чтобы было понятно, что исправлять
+			if !strings.Contains(err.Error(), strings.TrimSuffix(tt.env, "_FILE")) {
+				t.Errorf("error %q doesn't name the variable", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		field  string
+	}{
+		{"no mail transport", func(c *Config) { c.Mail.Transport = "" }, "mail.transport"},
+		{"port out of range", func(c *Config) { c.GRPC.Port = 70000 }, "grpc.port"},
+		{"pre stop delay too long", func(c *Config) { c.GRPC.PreStopDelay = c.GRPC.DrainTimeout }, "grpc.pre_stop_delay"},
+		{"zero mail token ttl", func(c *Config) { c.Cache.MailTokenTTL = 0 }, "cache.mail_token_ttl"},
+		{"short key overlap", func(c *Config) { c.Keys.Overlap = c.App.RefreshTokenTTL }, "keys.overlap"},
+		{"weak bcrypt", func(c *Config) { c.App.BcryptCost = 4 }, "app.bcrypt_cost"},
+		{"smtp without host", func(c *Config) { c.Mail.Transport = "smtp" }, "mail.smtp.host"},
+		{"unknown store", func(c *Config) { c.RateLimit.Store = "redis" }, "rate_limit.store"},
+		{"unknown method", func(c *Config) { c.RateLimit.Methods["Login"] = Limit{Rate: 1, Burst: 1} }, "rate_limit.methods"},
+		{"limit without rate", func(c *Config) { c.RateLimit.PerUser = Limit{Burst: 1} }, "rate_limit.per_user.rate"},
+		{"bad allowlist", func(c *Config) { c.RateLimit.Allowlist = []string{"10.0.0.1"} }, "rate_limit.allowlist"},
+		{"health on grpc port", func(c *Config) { c.Health.HTTPPort = c.GRPC.Port }, "health.http_port"},
+		{"otlp without endpoint", func(c *Config) { c.Tracing.Exporter = "otlp" }, "tracing.endpoint"},
+		{"sample ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.field+":") {
+				t.Errorf("error %q doesn't mention %s", err, tt.field)
+			}
+		})
+	}
+
+	t.Run("all errors at once", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.GRPC.Port = 0
+		cfg.Keys.Dir = ""
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "grpc.port") || !strings.Contains(err.Error(), "keys.dir") {
+			t.Errorf("%v, want both errors", err)
+		}
+	})
+
+	t.Run("default methods are known", func(t *testing.T) {
+		for method := range Default().RateLimit.Methods {
+			if !isMethod(method) {
+				t.Errorf("unknown default method %s", method)
+			}
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Любое поле конфига, кроме словарей, переопределяется переменной окружения
+// EnvPrefix + путь из yaml тегов в верхнем регистре через "_", например
+// USER_SERVICE_GRPC_PORT или USER_SERVICE_MAIL_SMTP_HOST.
+// Для секретов переменная с суффиксом _FILE задает файл со значением.
+// Списки задаются через запятую
+const EnvPrefix = "USER_SERVICE"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	secretType   = reflect.TypeOf(Secret(""))
+)
+
+func applyEnv(cfg *Config) error {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix)
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := applyEnvStruct(f, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := applyEnvField(f, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func applyEnvField(f reflect.Value, name string) error {
+	if f.Type() == secretType {
+		if path, ok := os.LookupEnv(name + "_FILE"); ok {
+			s, err := readSecretFile(path)
+			if err != nil {
+				return err
+			}
+			f.SetString(string(s))
+			return nil
+		}
+	}
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(val)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", f.Type())
+		}
+		var list []string
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		return fmt.Errorf("must be set in config file")
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+// Полные имена методов gRPC, ключи rate_limit.methods. Дублируют константы
+// сгенерированного пакета user, чтобы конфиг не зависел от него, совпадение
+// проверяет тест в handler
+const (
+	MethodRegister              = "/User/Register"
+	MethodLogin                 = "/User/Login"
+	MethodSendEmailConfirmation = "/User/SendEmailConfirmation"
+	MethodConfirmEmail          = "/User/ConfirmEmail"
+	MethodGetNewAccessToken     = "/User/GetNewAccessToken"
+	MethodLogout                = "/User/Logout"
+	MethodLogoutAll             = "/User/LogoutAll"
+	MethodUnlockAccount         = "/User/UnlockAccount"
+	MethodRequestPasswordReset  = "/User/RequestPasswordReset"
+	MethodResetPassword         = "/User/ResetPassword"
+	MethodUpdateProfile         = "/User/UpdateProfile"
+	MethodRequestEmailChange    = "/User/RequestEmailChange"
+	MethodConfirmEmailChange    = "/User/ConfirmEmailChange"
+	MethodDeleteAccount         = "/User/DeleteAccount"
+	MethodGetUserByID           = "/User/GetUserByID"
+	MethodGetUsersByEmail       = "/User/GetUsersByEmail"
+	MethodPromoteModer          = "/User/PromoteModer"
+	MethodPromoteAdmin          = "/User/PromoteAdmin"
+	MethodPromoteCoreAdmin      = "/User/PromoteCoreAdmin"
+	MethodGetAuditLog           = "/User/GetAuditLog"
+	MethodAdminDeleteUser       = "/User/AdminDeleteUser"
+	MethodBanUser               = "/User/BanUser"
+	MethodUnbanUser             = "/User/UnbanUser"
+	MethodDemoteModer           = "/User/DemoteModer"
+	MethodDemoteAdmin           = "/User/DemoteAdmin"
+	MethodDemoteCoreAdmin       = "/User/DemoteCoreAdmin"
+	MethodChangePassword        = "/User/ChangePassword"
+	MethodCheckPermissions      = "/User/CheckPermissions"
+	MethodCheckPermissionsBatch = "/User/CheckPermissionsBatch"
+	MethodGetRSAPublicKey       = "/User/GetRSAPublicKey"
+	MethodGetJWKS               = "/User/GetJWKS"
+)
+
+// все методы сервиса, для проверки rate_limit.methods
+var Methods = []string{
+	MethodRegister,
+	MethodLogin,
+	MethodSendEmailConfirmation,
+	MethodConfirmEmail,
+	MethodGetNewAccessToken,
+	MethodLogout,
+	MethodLogoutAll,
+	MethodUnlockAccount,
+	MethodRequestPasswordReset,
+	MethodResetPassword,
+	MethodUpdateProfile,
+	MethodRequestEmailChange,
+	MethodConfirmEmailChange,
+	MethodDeleteAccount,
+	MethodGetUserByID,
+	MethodGetUsersByEmail,
+	MethodPromoteModer,
+	MethodPromoteAdmin,
+	MethodPromoteCoreAdmin,
+	MethodGetAuditLog,
+	MethodAdminDeleteUser,
+	MethodBanUser,
+	MethodUnbanUser,
+	MethodDemoteModer,
+	MethodDemoteAdmin,
+	MethodDemoteCoreAdmin,
+	MethodChangePassword,
+	MethodCheckPermissions,
+	MethodCheckPermissionsBatch,
+	MethodGetRSAPublicKey,
+	MethodGetJWKS,
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret задается строкой или ссылкой на файл:
+//
+//	dsn: postgres://...
+//	dsn:
+//	  file: /run/secrets/dsn
+//
+// в окружении файл задается переменной с суффиксом _FILE, см. env.go.
+// при выводе конфига значение скрывается
+type Secret string
+
+const redacted = "[redacted]"
+
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Secret(node.Value)
+		return nil
+	}
+	var ref struct {
+		File string `yaml:"file"`
+	}
+	if err := node.Decode(&ref); err != nil {
+		return err
+	}
+	if ref.File == "" {
+		return fmt.Errorf("line %d: secret must be a string or {file: path}", node.Line)
+	}
+	v, err := readSecretFile(ref.File)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	if s == "" {
+		return "", nil
+	}
+	return redacted, nil
+}
+
+// не дает секрету попасть в лог через %v
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// перевод строки в конце файла добавляют почти все редакторы, он не часть секрета
+func readSecretFile(path string) (Secret, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Secret(strings.TrimRight(string(b), "\r\n")), nil
+}
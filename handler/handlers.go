@@ -3,23 +3,51 @@ package handler
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/glekoz/online-shop_proto/user"
 	"github.com/glekoz/online-shop_user/shared/models"
 	"github.com/glekoz/online-shop_user/shared/validator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type AppAPI interface {
-	Register(ctx context.Context, name, email, barePassword string) (access string, refresh string, err error)
+	Register(ctx context.Context, name, email, barePassword, locale string) (access string, refresh string, err error)
 	Login(ctx context.Context, email, barePassword string) (access string, refresh string, err error)
 	RequestEmailConfirmation(ctx context.Context, userID string) error
 	ConfirmEmail(ctx context.Context, userID, mailtoken string) error
+	UpdateProfile(ctx context.Context, upd models.ProfileUpdate) (models.User, error)
+	RequestEmailChange(ctx context.Context, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, userID, mailtoken string) error
+	RefreshTokens(ctx context.Context, refresh string) (access string, newRefresh string, err error)
+	Logout(ctx context.Context, access, refresh string) error
+	LogoutAll(ctx context.Context) error
+	UnlockAccount(ctx context.Context, userID, unlocktoken string) error
+	ResetPasswordRequest(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, userID, resettoken, newBarePassword string) error
+	DeleteAccount(ctx context.Context, barePassword string) (time.Time, error)
+	GetUserByID(ctx context.Context, userID string) (models.User, error)
+	GetUsersByEmail(ctx context.Context, email string) ([]models.UserInfo, error)
+	PromoteModer(ctx context.Context, userID string) error
+	PromoteAdmin(ctx context.Context, userID string) error
+	PromoteCoreAdmin(ctx context.Context, userID string) error
+	GetAuditLog(ctx context.Context, f models.AuditFilter, cursor string) ([]models.AuditEntry, string, error)
+	AdminDeleteUser(ctx context.Context, userID string) error
+	BanUser(ctx context.Context, userID string) error
+	UnbanUser(ctx context.Context, userID string) error
+	DemoteModer(ctx context.Context, userID string) error
+	DemoteAdmin(ctx context.Context, userID string) error
+	DemoteCoreAdmin(ctx context.Context, userID string) error
+	ChangePassword(ctx context.Context, currentBarePassword, newBarePassword string) (access string, refresh string, err error)
 
-	ParseJWTToken(tokenString string) (models.UserToken, error)
-	CreateJWTToken(userID, name string, isModer, isAdmin, isCore bool) (string, error)
-	GetRSAPublicKey() ([]byte, error)
+	CheckPermissions(ctx context.Context, userID string) (models.Permissions, error)
+	CheckPermissionsBatch(ctx context.Context, userIDs []string) ([]models.Permissions, error)
+
+	ParseAccessToken(tokenString string) (models.UserToken, error)
+	GetRSAPublicKey() (models.JWK, error)
+	GetJWKS() ([]models.JWK, error)
 }
 
 func (us *UserService) Register(ctx context.Context, req *user.RegisterUserRequest) (*user.LogRegResponse, error) {
@@ -27,6 +55,7 @@ func (us *UserService) Register(ctx context.Context, req *user.RegisterUserReque
 		Username: req.GetUsername(),
 		Password: req.GetPassword(),
 		Email:    req.GetEmail(),
+		Locale:   req.GetLocale(),
 	}
 	v := validator.New()
 	userreq.Validate(v)
@@ -37,7 +66,7 @@ func (us *UserService) Register(ctx context.Context, req *user.RegisterUserReque
 		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]string{"username": userreq.Username, "email": userreq.Email})
 		return logRegBadRequestResponse(v)
 	}
-	access, refresh, err := us.app.Register(ctx, userreq.Username, userreq.Email, userreq.Password)
+	access, refresh, err := us.app.Register(ctx, userreq.Username, userreq.Email, userreq.Password, userreq.Locale)
 	if err != nil {
 		return nil, us.handleError(ctx, err, "input data", map[string]string{"email": userreq.Email})
 	}
@@ -97,24 +126,403 @@ func (us *UserService) ConfirmEmail(ctx context.Context, req *user.ConfirmEmailR
 	return &user.Empty{}, nil
 }
 
+// частичное обновление: меняются только поля из маски
+func (us *UserService) UpdateProfile(ctx context.Context, req *user.UpdateProfileRequest) (*user.Profile, error) {
+	p := req.GetProfile()
+	a := p.GetShippingAddress()
+	userreq := models.UpdateProfileReq{
+		Paths:    req.GetUpdateMask().GetPaths(),
+		Name:     p.GetName(),
+		Birthday: p.GetBirthday(),
+		Phone:    p.GetPhone(),
+		ShippingAddress: models.Address{
+			Country:    a.GetCountry(),
+			City:       a.GetCity(),
+			Street:     a.GetStreet(),
+			PostalCode: a.GetPostalCode(),
+		},
+		Locale: p.GetLocale(),
+	}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]any{"update mask": userreq.Paths})
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	u, err := us.app.UpdateProfile(ctx, userreq.ProfileUpdate())
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return profileResponse(u), nil
+}
+
+func (us *UserService) RequestEmailChange(ctx context.Context, req *user.Email) (*user.Empty, error) {
+	userreq := models.EmailReq{Email: req.GetEmail()}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]string{"email": userreq.Email})
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	email := userreq.Email
+	err := us.app.RequestEmailChange(ctx, email)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"email": email})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) ConfirmEmailChange(ctx context.Context, req *user.ConfirmEmailRequest) (*user.Empty, error) {
+	userID := req.GetUserID()
+	if userID == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("user id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"user id": "must be provided"})
+	}
+	mailToken := req.GetMailToken()
+	if mailToken == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("mail token", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"mail token": "must be provided"})
+	}
+	err := us.app.ConfirmEmailChange(ctx, userID, mailToken)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Empty{}, nil
+}
+
 // валидация рефреш токена будет проведена и на фронте, поэтому вызов этого метода
-// можно прогнать через любой интерцептор
+// можно прогнать через любой интерцептор.
+// рефреш токен одноразовый, поэтому вместе с аксесс токеном возвращается новый рефреш
 func (us *UserService) GetNewAccessToken(ctx context.Context, req *user.Token) (*user.Token, error) {
 	refresh := req.GetToken()
 	if refresh == "" {
 		us.logger.InfoContext(ctx, "validation failed", slog.String("refresh token", "must be provided"))
 		return nil, badRequestResponse("validation", map[string]string{"refresh token": "must be provided"})
 	}
-	u, err := us.app.ParseJWTToken(refresh)
+	access, newRefresh, err := us.app.RefreshTokens(ctx, refresh)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Token{Token: access, RefreshToken: newRefresh}, nil
+}
+
+// аксесс токен уже проверен в интерцепторе, здесь он нужен, чтобы положить его в денайлист
+func (us *UserService) Logout(ctx context.Context, req *user.Token) (*user.Empty, error) {
+	refresh := req.GetToken()
+	if refresh == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("refresh token", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"refresh token": "must be provided"})
+	}
+	access, err := readExactlyOneValueFromMD(ctx, AuthKey, "user must be authenticated", codes.Unauthenticated)
 	if err != nil {
-		us.logger.InfoContext(ctx, "token parsing", "error", err.Error())
-		return nil, badRequestResponse("parsing", map[string]string{"refresh token": "badly structured"})
+		return nil, err
 	}
-	access, err := us.app.CreateJWTToken(u.ID, u.Name, u.IsModer, u.IsAdmin, u.IsCore)
+	err = us.app.Logout(ctx, access, refresh)
 	if err != nil {
 		return nil, us.handleError(ctx, err)
 	}
-	return &user.Token{Token: access}, nil
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) LogoutAll(ctx context.Context, req *user.Empty) (*user.Empty, error) {
+	err := us.app.LogoutAll(ctx)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Empty{}, nil
+}
+
+// токен из письма проверяется до поиска пользователя, поэтому без верного токена
+// ответ не зависит от того, существует ли пользователь
+func (us *UserService) UnlockAccount(ctx context.Context, req *user.ConfirmEmailRequest) (*user.Empty, error) {
+	userID := req.GetUserID()
+	if userID == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("user id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"user id": "must be provided"})
+	}
+	token := req.GetMailToken()
+	if token == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("mail token", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"mail token": "must be provided"})
+	}
+	err := us.app.UnlockAccount(ctx, userID, token)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) RequestPasswordReset(ctx context.Context, req *user.Email) (*user.Empty, error) {
+	userreq := models.EmailReq{Email: req.GetEmail()}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]string{"email": userreq.Email})
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	email := userreq.Email
+	err := us.app.ResetPasswordRequest(ctx, email)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"email": email})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) ResetPassword(ctx context.Context, req *user.ResetPasswordRequest) (*user.Empty, error) {
+	userreq := models.ResetPasswordReq{
+		UserID:      req.GetUserID(),
+		ResetToken:  req.GetResetToken(),
+		NewPassword: req.GetNewPassword(),
+	}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]string{"user id": userreq.UserID})
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	err := us.app.ResetPassword(ctx, userreq.UserID, userreq.ResetToken, userreq.NewPassword)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) (*user.LogRegResponse, error) {
+	userreq := models.ChangePasswordReq{
+		CurrentPassword: req.GetCurrentPassword(),
+		NewPassword:     req.GetNewPassword(),
+	}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed")
+		return logRegBadRequestResponse(v)
+	}
+	access, refresh, err := us.app.ChangePassword(ctx, userreq.CurrentPassword, userreq.NewPassword)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.LogRegResponse{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (us *UserService) DeleteAccount(ctx context.Context, req *user.DeleteAccountRequest) (*user.DeleteAccountResponse, error) {
+	password := req.GetPassword()
+	if password == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("password", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"password": "must be provided"})
+	}
+	at, err := us.app.DeleteAccount(ctx, password)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.DeleteAccountResponse{ScheduledAt: timestamppb.New(at)}, nil
+}
+
+func (us *UserService) GetUserByID(ctx context.Context, req *user.UserID) (*user.Profile, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	u, err := us.app.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return profileResponse(u), nil
+}
+
+func (us *UserService) GetUsersByEmail(ctx context.Context, req *user.Email) (*user.Users, error) {
+	userreq := models.EmailPrefixReq{Email: req.GetEmail()}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "input data", map[string]string{"email": userreq.Email})
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	users, err := us.app.GetUsersByEmail(ctx, userreq.Email)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"email": userreq.Email})
+	}
+	return usersResponse(users), nil
+}
+
+func (us *UserService) PromoteModer(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.PromoteModer(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) PromoteAdmin(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.PromoteAdmin(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) PromoteCoreAdmin(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.PromoteCoreAdmin(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) GetAuditLog(ctx context.Context, req *user.AuditLogRequest) (*user.AuditLogPage, error) {
+	userreq := models.AuditLogReq{
+		Filter: models.AuditFilter{
+			TargetID: req.GetUserID(),
+			ActorID:  req.GetActorID(),
+			Action:   req.GetAction(),
+			Limit:    int(req.GetLimit()),
+		},
+		Cursor: req.GetCursor(),
+	}
+	if req.GetFrom() != nil {
+		userreq.Filter.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		userreq.Filter.To = req.GetTo().AsTime()
+	}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "errors", v.Errors)
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	entries, next, err := us.app.GetAuditLog(ctx, userreq.Filter, userreq.Cursor)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return auditLogResponse(entries, next), nil
+}
+
+func (us *UserService) AdminDeleteUser(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.AdminDeleteUser(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) BanUser(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.BanUser(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) UnbanUser(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.UnbanUser(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) DemoteModer(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.DemoteModer(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) DemoteAdmin(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.DemoteAdmin(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+func (us *UserService) DemoteCoreAdmin(ctx context.Context, req *user.UserID) (*user.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	err := us.app.DemoteCoreAdmin(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return &user.Empty{}, nil
+}
+
+// для других сервисов, токен сервиса проверяется в интерсепторе
+func (us *UserService) CheckPermissions(ctx context.Context, req *user.UserID) (*user.Permissions, error) {
+	id := req.GetId()
+	if id == "" {
+		us.logger.InfoContext(ctx, "validation failed", slog.String("id", "must be provided"))
+		return nil, badRequestResponse("validation", map[string]string{"id": "must be provided"})
+	}
+	p, err := us.app.CheckPermissions(ctx, id)
+	if err != nil {
+		return nil, us.handleError(ctx, err, "input data", map[string]string{"id": id})
+	}
+	return permissionsResponse(p), nil
+}
+
+func (us *UserService) CheckPermissionsBatch(ctx context.Context, req *user.UserIDs) (*user.PermissionsBatch, error) {
+	userreq := models.UserIDsReq{IDs: req.GetIds()}
+	v := validator.New()
+	userreq.Validate(v)
+	if !v.Valid() {
+		us.logger.InfoContext(ctx, "validation failed", "errors", v.Errors)
+		return nil, badRequestResponse("validation", v.Errors)
+	}
+	ps, err := us.app.CheckPermissionsBatch(ctx, userreq.IDs)
+	if err != nil {
+		return nil, us.handleError(ctx, err)
+	}
+	res := &user.PermissionsBatch{Permissions: make([]*user.Permissions, 0, len(ps))}
+	for _, p := range ps {
+		res.Permissions = append(res.Permissions, permissionsResponse(p))
+	}
+	return res, nil
 }
 
 // опционально защитить проверкой, чтобы только мои сервисы могли запрашивать
@@ -125,11 +533,20 @@ func (us *UserService) GetRSAPublicKey(ctx context.Context, req *user.Empty) (*u
 		us.logger.ErrorContext(ctx, "PKIX generating failed", "error", err.Error())
 		return nil, status.Error(codes.Internal, "PKIX generating failed")
 	}
-	return &user.RSAPublicKey{
-		Kty: "RSA",
-		Use: "sig",
-		Kid: "1",
-		Alg: "RS384",
-		Key: pub,
-	}, nil
+	return rsaPublicKeyResponse(pub), nil
+}
+
+// во время ротации ключей токены могут быть подписаны разными ключами,
+// поэтому другие сервисы должны выбирать ключ по kid из заголовка токена
+func (us *UserService) GetJWKS(ctx context.Context, req *user.Empty) (*user.JWKS, error) {
+	pubs, err := us.app.GetJWKS()
+	if err != nil {
+		us.logger.ErrorContext(ctx, "PKIX generating failed", "error", err.Error())
+		return nil, status.Error(codes.Internal, "PKIX generating failed")
+	}
+	res := &user.JWKS{Keys: make([]*user.RSAPublicKey, 0, len(pubs))}
+	for _, pub := range pubs {
+		res.Keys = append(res.Keys, rsaPublicKeyResponse(pub))
+	}
+	return res, nil
 }
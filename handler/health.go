@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/glekoz/online-shop_proto/user"
+	"github.com/glekoz/online-shop_user/config"
+)
+
+// проверка зависимости, nil - зависимость доступна
+type HealthCheck func(ctx context.Context) error
+
+// Health периодически проверяет зависимости и публикует результат в grpc.health.v1:
+// у каждой зависимости свое имя сервиса (например, "postgres"), а пустое имя и имя
+// сервиса User - SERVING, только если доступны все обязательные. Информационные
+// зависимости (почта) только публикуются: очередь писем переживает их недоступность,
+// и из-за них сервис не должен выводиться из балансировки. Они обычно ходят
+// к внешнему провайдеру, поэтому проверяются реже, раз в cfg.InfoCheckInterval.
+// Тот же результат отдается по HTTP для оркестраторов, которые не умеют проверять gRPC.
+// После Shutdown статус остается NOT_SERVING до конца работы процесса
+type Health struct {
+	srv          *health.Server
+	logger       *slog.Logger
+	checks       map[string]HealthCheck
+	info         map[string]HealthCheck
+	interval     time.Duration
+	infoInterval time.Duration
+	timeout      time.Duration
+
+	mu       sync.RWMutex
+	results  map[string]error // nil в map - проверка прошла, нет ключа - еще не выполнялась
+	shutdown bool
+}
+
+// checks влияют на готовность, info - нет. имена в них не должны совпадать
+func NewHealth(l *slog.Logger, cfg config.Health, checks, info map[string]HealthCheck) *Health {
+	h := &Health{
+		srv:          health.NewServer(),
+		logger:       l,
+		checks:       checks,
+		info:         info,
+		interval:     cfg.CheckInterval,
+		infoInterval: cfg.InfoCheckInterval,
+		timeout:      cfg.CheckTimeout,
+		results:      make(map[string]error, len(checks)+len(info)),
+	}
+	// до первой проверки сервис не готов
+	h.srv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	h.srv.SetServingStatus(user.User_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	for name := range checks {
+		h.srv.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	for name := range info {
+		h.srv.SetServingStatus(name, healthpb.HealthCheckResponse_UNKNOWN)
+	}
+	return h
+}
+
+func (h *Health) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, h.srv)
+}
+
+// Run проверяет все зависимости сразу, затем обязательные раз в interval,
+// а информационные раз в infoInterval, пока не отменен ctx
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	info := time.NewTicker(h.infoInterval)
+	defer info.Stop()
+	h.check(ctx, h.checks, h.info)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.check(ctx, h.checks)
+		case <-info.C:
+			h.check(ctx, h.info)
+		}
+	}
+}
+
+// проверки идут параллельно, чтобы одна зависшая зависимость не задерживала остальные
+func (h *Health) check(ctx context.Context, sets ...map[string]HealthCheck) {
+	results := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, checks := range sets {
+		for name, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cctx, cancel := context.WithTimeout(ctx, h.timeout)
+				defer cancel()
+				err := check(cctx)
+				mu.Lock()
+				results[name] = err
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// проверки прерваны остановкой, их результат ничего не говорит о зависимостях
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.shutdown {
+		return
+	}
+	for name, err := range results {
+		prev, checked := h.results[name]
+		h.results[name] = err
+		st := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		h.srv.SetServingStatus(name, st)
+		switch {
+		case err != nil && (!checked || prev == nil):
+			h.logger.ErrorContext(ctx, "dependency is unavailable", "dependency", name, "error", err.Error())
+		case err == nil && checked && prev != nil:
+			h.logger.InfoContext(ctx, "dependency is available again", "dependency", name)
+		}
+	}
+	st := healthpb.HealthCheckResponse_SERVING
+	if !h.readyLocked() {
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.srv.SetServingStatus("", st)
+	h.srv.SetServingStatus(user.User_ServiceDesc.ServiceName, st)
+}
+
+// переводит все статусы в NOT_SERVING, чтобы балансировщики перестали слать запросы
+// до остановки сервера
+func (h *Health) Shutdown() {
+	h.mu.Lock()
+	h.shutdown = true
+	h.mu.Unlock()
+	h.srv.Shutdown()
+}
+
+// готов, если все обязательные проверки выполнены и прошли и остановка не началась
+func (h *Health) Ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.readyLocked()
+}
+
+func (h *Health) readyLocked() bool {
+	if h.shutdown {
+		return false
+	}
+	for name := range h.checks {
+		if err, checked := h.results[name]; !checked || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+	Info   map[string]string `json:"info,omitempty"` // на status не влияют
+}
+
+// HTTPHandler отдает /healthz (процесс жив) и /readyz (можно слать запросы).
+// тексты ошибок не отдаются, они есть в логах
+func (h *Health) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		resp := h.readiness()
+		code := http.StatusOK
+		if resp.Status != healthpb.HealthCheckResponse_SERVING.String() {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	})
+	return mux
+}
+
+func (h *Health) readiness() readiness {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	resp := readiness{
+		Status: healthpb.HealthCheckResponse_NOT_SERVING.String(),
+		Checks: h.statuses(h.checks),
+	}
+	if len(h.info) > 0 {
+		resp.Info = h.statuses(h.info)
+	}
+	if h.readyLocked() {
+		resp.Status = healthpb.HealthCheckResponse_SERVING.String()
+	}
+	return resp
+}
+
+func (h *Health) statuses(checks map[string]HealthCheck) map[string]string {
+	res := make(map[string]string, len(checks))
+	for name := range checks {
+		st := healthpb.HealthCheckResponse_UNKNOWN
+		if err, checked := h.results[name]; checked {
+			st = healthpb.HealthCheckResponse_SERVING
+			if err != nil {
+				st = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+		}
+		res[name] = st.String()
+	}
+	return res
+}
+
+// проверки здоровья приходят от оркестратора напрямую, без метаданных шлюза
+// и токенов, поэтому interceptor к ним не применяется
+func skipHealth(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
@@ -3,14 +3,19 @@ package handler
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/glekoz/online-shop_proto/user"
 	"github.com/glekoz/online-shop_user/app"
 	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
 	"github.com/glekoz/online-shop_user/shared/validator"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func badRequestResponse(reason string, metadata map[string]string) error {
@@ -40,26 +45,165 @@ func logRegBadRequestResponse(v *validator.Validator) (*user.LogRegResponse, err
 	return nil, err
 }
 
+func rsaPublicKeyResponse(pub models.JWK) *user.RSAPublicKey {
+	return &user.RSAPublicKey{
+		Kty: "RSA",
+		Use: "sig",
+		Kid: pub.ID,
+		Alg: "RS384",
+		Key: pub.PKIX,
+		N:   pub.N,
+		E:   pub.E,
+	}
+}
+
+func profileResponse(u models.User) *user.Profile {
+	var birthday string
+	if !u.Birthday.IsZero() {
+		birthday = u.Birthday.Format(models.BirthdayLayout)
+	}
+	return &user.Profile{
+		Id:             u.ID,
+		Name:           u.Name,
+		Email:          u.Email,
+		EmailConfirmed: u.IsEmailConfirmed,
+		PendingEmail:   u.PendingEmail,
+		Birthday:       birthday,
+		Phone:          u.Phone,
+		ShippingAddress: &user.Address{
+			Country:    u.ShippingAddress.Country,
+			City:       u.ShippingAddress.City,
+			Street:     u.ShippingAddress.Street,
+			PostalCode: u.ShippingAddress.PostalCode,
+		},
+		Locale: u.Locale,
+	}
+}
+
+func usersResponse(users []models.UserInfo) *user.Users {
+	res := &user.Users{Users: make([]*user.UserInfo, 0, len(users))}
+	for _, u := range users {
+		res.Users = append(res.Users, &user.UserInfo{
+			Id:             u.ID,
+			Name:           u.Name,
+			Email:          u.Email,
+			EmailConfirmed: u.IsEmailConfirmed,
+			IsModer:        slices.Contains(u.Roles, models.RoleModer),
+			IsAdmin:        slices.Contains(u.Roles, models.RoleAdmin),
+			IsCore:         slices.Contains(u.Roles, models.RoleCoreAdmin),
+			Roles:          u.Roles,
+		})
+	}
+	return res
+}
+
+func permissionsResponse(p models.Permissions) *user.Permissions {
+	return &user.Permissions{
+		Id:             p.UserID,
+		Found:          p.Found,
+		EmailConfirmed: p.IsEmailConfirmed,
+		Banned:         p.IsBanned,
+		IsModer:        slices.Contains(p.Roles, models.RoleModer),
+		IsAdmin:        slices.Contains(p.Roles, models.RoleAdmin),
+		IsCore:         slices.Contains(p.Roles, models.RoleCoreAdmin),
+		Roles:          p.Roles,
+		Permissions:    p.Permissions,
+	}
+}
+
+func auditLogResponse(entries []models.AuditEntry, next string) *user.AuditLogPage {
+	res := &user.AuditLogPage{Entries: make([]*user.AuditEntry, 0, len(entries)), NextCursor: next}
+	for _, e := range entries {
+		res.Entries = append(res.Entries, &user.AuditEntry{
+			Id:        e.ID,
+			ActorID:   e.ActorID,
+			UserID:    e.TargetID,
+			Action:    e.Action,
+			Ip:        e.IP,
+			Before:    string(e.Before),
+			After:     string(e.After),
+			CreatedAt: timestamppb.New(e.CreatedAt),
+		})
+	}
+	return res
+}
+
+// фронтенд по reason решает, показать капчу или таймер, а по RetryInfo - сколько ждать
+func retryResponse(msg, reason string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	st, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: "user.online-shop"},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter.Round(time.Second))},
+	)
+	if err != nil {
+		return status.Error(codes.Internal, "детали не добавились")
+	}
+	return st.Err()
+}
+
 func (us *UserService) handleError(ctx context.Context, err error, args ...any) error {
+	var retry *app.RetryError
 	switch {
+	case errors.As(err, &retry) && errors.Is(err, app.ErrAccountLocked):
+		us.logger.WarnContext(logger.ErrorCtx(ctx, err), app.ErrAccountLocked.Error(), args...)
+		return retryResponse("account is temporarily locked, check your email to unlock it", "ACCOUNT_LOCKED", retry.RetryAfter)
+	case errors.As(err, &retry):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), retry.Err.Error(), args...)
+		return retryResponse("too many failed login attempts, try again later", "LOGIN_THROTTLED", retry.RetryAfter)
 	case errors.Is(err, app.ErrUserAlreadyExists):
 		us.logger.InfoContext(ctx, app.ErrUserAlreadyExists.Error(), args...)
 		return status.Error(codes.AlreadyExists, "user with the same email already exists")
 	case errors.Is(err, app.ErrInvalidCredentials):
 		us.logger.InfoContext(ctx, app.ErrInvalidCredentials.Error(), args...)
 		return status.Error(codes.Unauthenticated, "wrong email or password")
+	case errors.Is(err, app.ErrWrongPassword):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrWrongPassword.Error(), args...)
+		return status.Error(codes.PermissionDenied, "current password is wrong")
+	case errors.Is(err, app.ErrPasswordReused):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrPasswordReused.Error(), args...)
+		return status.Error(codes.FailedPrecondition, "new password must differ from the recently used ones")
+	case errors.Is(err, app.ErrInvalidRefreshToken):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrInvalidRefreshToken.Error(), args...)
+		return status.Error(codes.Unauthenticated, "refresh token is invalid, expired or revoked")
+	case errors.Is(err, app.ErrRefreshTokenReused):
+		us.logger.WarnContext(logger.ErrorCtx(ctx, err), app.ErrRefreshTokenReused.Error(), args...)
+		return status.Error(codes.Unauthenticated, "refresh token has already been used, please log in again")
 	case errors.Is(err, app.ErrNoRUID):
 		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrNoRUID.Error(), args...)
 		return status.Error(codes.Unauthenticated, "user is not authenticated")
 	case errors.Is(err, app.ErrRUIDneID):
 		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrRUIDneID.Error(), args...)
 		return status.Error(codes.PermissionDenied, "only the user can request email confirmation")
+	case errors.Is(err, app.ErrForbidden):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrForbidden.Error(), args...)
+		return status.Error(codes.PermissionDenied, "not enough rights for this operation")
+	case errors.Is(err, app.ErrInvalidCursor):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrInvalidCursor.Error(), args...)
+		return status.Error(codes.InvalidArgument, "invalid pagination cursor")
+	case errors.Is(err, app.ErrRoleAlreadyGranted):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrRoleAlreadyGranted.Error(), args...)
+		return status.Error(codes.AlreadyExists, "user already has this role")
+	case errors.Is(err, app.ErrSelfDemotion):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrSelfDemotion.Error(), args...)
+		return status.Error(codes.FailedPrecondition, "you can't demote yourself")
+	case errors.Is(err, app.ErrSelfBan):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrSelfBan.Error(), args...)
+		return status.Error(codes.FailedPrecondition, "you can't ban yourself")
+	case errors.Is(err, app.ErrHasHigherRole):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrHasHigherRole.Error(), args...)
+		return status.Error(codes.FailedPrecondition, "user has a higher role that must be removed first")
+	case errors.Is(err, app.ErrLastCoreAdmin):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrLastCoreAdmin.Error(), args...)
+		return status.Error(codes.FailedPrecondition, "the last core admin can't be demoted or deleted")
 	case errors.Is(err, app.ErrUserNotFound):
 		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrUserNotFound.Error(), args...)
 		return status.Error(codes.NotFound, "no user found")
 	case errors.Is(err, app.ErrEmailAlreadyConfirmed):
 		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrEmailAlreadyConfirmed.Error(), args...)
 		return status.Error(codes.AlreadyExists, "email already confirmed")
+	case errors.Is(err, app.ErrSameEmail):
+		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrSameEmail.Error(), args...)
+		return status.Error(codes.InvalidArgument, "new email is the same as the current one")
 	case errors.Is(err, app.ErrMsgAlreadySent):
 		us.logger.InfoContext(logger.ErrorCtx(ctx, err), app.ErrMsgAlreadySent.Error(), args...)
 		return status.Error(codes.FailedPrecondition, "confirmation letter has already been sent, check your email")
@@ -2,20 +2,26 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"time"
 
 	"github.com/glekoz/online-shop_proto/user"
+	"github.com/glekoz/online-shop_user/app"
+	"github.com/glekoz/online-shop_user/metrics"
 	"github.com/glekoz/online-shop_user/shared/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
 const AuthKey = "authorization"
 const IPAddress = "ipaddress"
+const ServiceKey = "service-token"
 
 func (us *UserService) RequireNoAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	switch info.FullMethod {
@@ -43,7 +49,24 @@ func (us *UserService) RequireAuthInterceptor(ctx context.Context, req any, info
 	case user.User_Login_FullMethodName:
 	case user.User_GetNewAccessToken_FullMethodName: // для этого метода неважно наличие или отсутствие токена
 	case user.User_GetRSAPublicKey_FullMethodName: // для этого тоже
-	default:
+	case user.User_GetJWKS_FullMethodName:
+	case user.User_RequestPasswordReset_FullMethodName: // пароль забыт, так что токена нет
+	case user.User_ResetPassword_FullMethodName:
+	case user.User_UnlockAccount_FullMethodName: // аккаунт заблокирован, войти нельзя
+	case user.User_CheckPermissions_FullMethodName, user.User_CheckPermissionsBatch_FullMethodName:
+		// эти методы вызывают сервисы, а не пользователи
+		token, err := readExactlyOneValueFromMD(ctx, ServiceKey, "service must be authenticated", codes.Unauthenticated)
+		if err != nil {
+			us.logger.InfoContext(ctx, "service must be authenticated")
+			return nil, err
+		}
+		service, ok := us.serviceByToken(token)
+		if !ok {
+			us.logger.WarnContext(ctx, "client provides invalid service token")
+			return nil, status.Error(codes.Unauthenticated, "invalid service token")
+		}
+		ctx = logger.WithService(ctx, service)
+	default: // в том числе админские методы: права проверяются в app по id из токена
 		md, ok := metadata.FromIncomingContext(ctx)
 		if !ok || len(md.Get(AuthKey)) < 1 {
 			us.logger.InfoContext(ctx, "user must be authenticated")
@@ -54,7 +77,11 @@ func (us *UserService) RequireAuthInterceptor(ctx context.Context, req any, info
 			return nil, status.Error(codes.InvalidArgument, "client provides too much tokens")
 		}
 		token := md.Get(AuthKey)[0]
-		u, err := us.app.ParseJWTToken(token)
+		u, err := us.app.ParseAccessToken(token)
+		if errors.Is(err, app.ErrTokenRevoked) {
+			us.logger.InfoContext(ctx, "client provides revoked token")
+			return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+		}
 		if err != nil || u.ID == "" {
 			us.logger.InfoContext(ctx, "client provides invalid token")
 			return nil, status.Error(codes.Unauthenticated, "client provides invalid token")
@@ -80,19 +107,33 @@ func (us *UserService) PanicRecoverer(ctx context.Context, req any, info *grpc.U
 	return resp, err
 }
 
-// rate limiter будет первым, чтобы извлечь из метаданных контекста айпи адрес
+// rate limiter будет первым, чтобы извлечь из метаданных контекста айпи адрес.
+// внутренние сервисы (по адресу или по токену сервиса) не ограничиваются
 func (us *UserService) RateLimiter(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-	ip, err := readExactlyOneValueFromMD(ctx, IPAddress, "no IP address provided", codes.Internal)
+	rawIP, err := readExactlyOneValueFromMD(ctx, IPAddress, "no IP address provided", codes.Internal)
 	if err != nil {
 		us.logger.Error("no IP address provided")
 		return nil, err
 	}
 	ctx = logger.WithMethod(ctx, info.FullMethod)
+	// адрес попадает в ключи лимитов и счетчиков входа, поэтому принимается только
+	// настоящий IP в каноническом виде: иначе каждый вариант записи давал бы свой
+	// счетчик, а слишком длинное значение не помещалось бы в БД
+	addr, err := netip.ParseAddr(rawIP)
+	if err != nil {
+		us.logger.WarnContext(ctx, "invalid IP address provided", "ip", rawIP)
+		return nil, status.Error(codes.InvalidArgument, "invalid IP address")
+	}
+	ip := addr.WithZone("").Unmap().String()
 	ctx = logger.WithIPAddress(ctx, ip)
-	err = us.rl.Allow(ip)
+	if us.isAllowlisted(ctx) || us.isServiceCall(ctx) {
+		return handler(ctx, req)
+	}
+	err = us.rl.AllowIP(ctx, info.FullMethod, ip)
 	if err != nil {
-		us.logger.InfoContext(ctx, err.Error())
-		return nil, status.Error(codes.ResourceExhausted, err.Error())
+		if err = us.rateLimitResponse(ctx, metrics.LimitIP, err); err != nil {
+			return nil, err
+		}
 	}
 
 	resp, err := handler(ctx, req)
@@ -100,6 +141,77 @@ func (us *UserService) RateLimiter(ctx context.Context, req any, info *grpc.Unar
 	return resp, err
 }
 
+// allowlist сверяется с адресом соединения, а не с метаданными ipaddress:
+// их заполняет клиент, и подставить туда адрес из allowlist может кто угодно
+func (us *UserService) isAllowlisted(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return false
+	}
+	ap, err := netip.ParseAddrPort(p.Addr.String())
+	if err != nil {
+		return false
+	}
+	return us.rl.IsAllowlisted(ap.Addr().String())
+}
+
+// идет после RequireAuthInterceptor, чтобы id пользователя уже был в контексте
+func (us *UserService) UserRateLimiter(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ld, ok := ctx.Value(logger.LogDataKey).(logger.LogData)
+	if ok && ld.UserID != "" && !us.isAllowlisted(ctx) {
+		if err := us.rl.AllowUser(ctx, ld.UserID); err != nil {
+			if err = us.rateLimitResponse(ctx, metrics.LimitUser, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := handler(ctx, req)
+
+	return resp, err
+}
+
+// при недоступном хранилище лимитов запрос пропускается, чтобы сбой БД
+// не положил получение публичного ключа и остальные методы. методы с FailClosed
+// (вход, регистрация, письма) в этом случае отклоняются: без лимита их можно перебирать
+func (us *UserService) rateLimitResponse(ctx context.Context, limit string, err error) error {
+	var rle *RateLimitError
+	if errors.As(err, &rle) {
+		us.metrics.RateLimited(limit)
+		us.logger.InfoContext(ctx, err.Error())
+		return retryResponse("rate limit exceeded", "RATE_LIMITED", rle.RetryAfter)
+	}
+	us.logger.ErrorContext(ctx, "rate limiter store failed", "error", err.Error())
+	var se *LimiterStoreError
+	if errors.As(err, &se) && se.FailClosed {
+		return status.Error(codes.Unavailable, "rate limiter is unavailable, try again later")
+	}
+	return nil
+}
+
+// токен сервиса проверяется еще раз в RequireAuthInterceptor, здесь только решается,
+// применять ли лимиты
+func (us *UserService) isServiceCall(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	tokens := md.Get(ServiceKey)
+	if len(tokens) != 1 {
+		return false
+	}
+	_, ok = us.serviceByToken(tokens[0])
+	return ok
+}
+
+// первый в цепочке, чтобы учитывались и запросы, отклоненные лимитами и аутентификацией
+func (us *UserService) RPCMetrics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	us.metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return resp, err
+}
+
 func (us *UserService) TimeCounter(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	start := time.Now()
 	us.logger.InfoContext(ctx, "incoming request", slog.String("start time", start.Format("02-01-2006 15:04:05")))
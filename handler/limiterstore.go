@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// хранилище ведер токенов. MemoryLimiterStore работает в пределах одного процесса,
+// repository.Repository хранит ведра в БД, и лимиты действуют на все реплики сразу
+type LimiterStore interface {
+	// возвращает 0, если токен забран, иначе время, через которое он появится
+	TakeToken(ctx context.Context, key string, limit rate.Limit, burst int) (time.Duration, error)
+	// удаляет ведра, которые давно не использовались
+	DeleteStaleBuckets(ctx context.Context) error
+}
+
+type Client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type MemoryLimiterStore struct {
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{clients: make(map[string]*Client)}
+}
+
+func (s *MemoryLimiterStore) TakeToken(ctx context.Context, key string, limit rate.Limit, burst int) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.clients[key]
+	if !found {
+		c = &Client{limiter: rate.NewLimiter(limit, burst)}
+		s.clients[key] = c
+	}
+	c.lastSeen = time.Now()
+
+	// резервация показывает, сколько ждать, и отменяется, если ждать нужно
+	r := c.limiter.Reserve()
+	if !r.OK() {
+		return rate.InfDuration, nil
+	}
+	if d := r.Delay(); d > 0 {
+		r.Cancel()
+		return d, nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryLimiterStore) DeleteStaleBuckets(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, client := range s.clients {
+		if time.Since(client.lastSeen) > 3*time.Minute {
+			delete(s.clients, key)
+		}
+	}
+	return nil
+}
@@ -1,58 +1,167 @@
 package handler
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
 	"sync"
 	"time"
 
+	"github.com/glekoz/online-shop_user/config"
 	"golang.org/x/time/rate"
 )
 
-type Client struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
+type Limit struct {
+	Rate       rate.Limit
+	Burst      int  // 0 - лимит не применяется
+	FailClosed bool // отклонять запросы, если хранилище лимитов недоступно
+}
+
+func (l Limit) enabled() bool {
+	return l.Burst > 0
+}
+
+type RateLimits struct {
+	Default   Limit            // по IP для методов без своей политики
+	Methods   map[string]Limit // по IP, ключ - полное имя метода, у каждого метода свое ведро
+	PerUser   Limit            // по id пользователя после аутентификации, общее ведро на все методы
+	Allowlist []netip.Prefix   // адреса внутренних сервисов, для них лимитов нет
+}
+
+// значения по умолчанию - в config.Default
+func NewRateLimits(cfg config.RateLimit) (RateLimits, error) {
+	limit := func(l config.Limit) Limit {
+		return Limit{Rate: rate.Limit(l.Rate), Burst: l.Burst, FailClosed: l.FailClosed}
+	}
+	limits := RateLimits{
+		Default: limit(cfg.Default),
+		Methods: make(map[string]Limit, len(cfg.Methods)),
+		PerUser: limit(cfg.PerUser),
+	}
+	for method, l := range cfg.Methods {
+		limits.Methods[method] = limit(l)
+	}
+	for _, s := range cfg.Allowlist {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return RateLimits{}, err
+		}
+		limits.Allowlist = append(limits.Allowlist, p.Masked())
+	}
+	return limits, nil
+}
+
+// хранилище лимитов недоступно. FailClosed - запрос надо отклонить,
+// иначе сбой БД снимал бы защиту от перебора
+type LimiterStoreError struct {
+	Err        error
+	FailClosed bool
+}
+
+func (e *LimiterStoreError) Error() string {
+	return "rate limiter store failed: " + e.Err.Error()
+}
+
+func (e *LimiterStoreError) Unwrap() error {
+	return e.Err
+}
+
+// клиенту нужно подождать RetryAfter
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
 }
 
 type RateLimiter struct {
-	mu      sync.Mutex
-	clients map[string]*Client
+	store  LimiterStore
+	limits RateLimits
+	logger *slog.Logger
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewRateLimiter() *RateLimiter {
-	rl := &RateLimiter{clients: make(map[string]*Client)}
-	rl.startCleaning()
+// store - NewMemoryLimiterStore для одной реплики или БД для нескольких
+func NewRateLimiter(l *slog.Logger, limits RateLimits, store LimiterStore) *RateLimiter {
+	rl := &RateLimiter{
+		store:  store,
+		limits: limits,
+		logger: l,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go rl.clean()
 	return rl
 }
 
-func (rl *RateLimiter) Allow(ip string) error {
-	rl.mu.Lock()
+// Close останавливает очистку старых ведер и ждет ее завершения,
+// само хранилище не закрывается
+func (rl *RateLimiter) Close() {
+	rl.closeOnce.Do(func() {
+		close(rl.stop)
+	})
+	<-rl.done
+}
 
-	if _, found := rl.clients[ip]; !found {
-		rl.clients[ip] = &Client{limiter: rate.NewLimiter(2, 4)}
+func (rl *RateLimiter) IsAllowlisted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
 	}
+	for _, p := range rl.limits.Allowlist {
+		if p.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
 
-	rl.clients[ip].lastSeen = time.Now()
-
-	if !rl.clients[ip].limiter.Allow() {
-		rl.mu.Unlock()
-		return errors.New("rate limit exceeded")
+func (rl *RateLimiter) AllowIP(ctx context.Context, method, ip string) error {
+	l, ok := rl.limits.Methods[method]
+	if !ok {
+		return rl.allow(ctx, "ip:default:"+ip, rl.limits.Default)
 	}
+	return rl.allow(ctx, "ip:"+method+":"+ip, l)
+}
 
-	rl.mu.Unlock()
+func (rl *RateLimiter) AllowUser(ctx context.Context, userID string) error {
+	return rl.allow(ctx, "user:"+userID, rl.limits.PerUser)
+}
+
+// превышение лимита - *RateLimitError, ошибка хранилища - *LimiterStoreError
+func (rl *RateLimiter) allow(ctx context.Context, key string, l Limit) error {
+	if !l.enabled() {
+		return nil
+	}
+	d, err := rl.store.TakeToken(ctx, key, l.Rate, l.Burst)
+	if err != nil {
+		return &LimiterStoreError{Err: err, FailClosed: l.FailClosed}
+	}
+	if d > 0 {
+		return &RateLimitError{RetryAfter: d}
+	}
 	return nil
 }
 
-func (rl *RateLimiter) startCleaning() {
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			rl.mu.Lock()
-			for ip, client := range rl.clients {
-				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(rl.clients, ip)
-				}
-			}
-			rl.mu.Unlock()
+func (rl *RateLimiter) clean() {
+	defer close(rl.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
 		}
-	}()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := rl.store.DeleteStaleBuckets(ctx); err != nil {
+			rl.logger.Error("failed to delete stale rate limit buckets", "error", err.Error())
+		}
+		cancel()
+	}
 }
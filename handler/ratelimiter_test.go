@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_proto/user"
+	"github.com/glekoz/online-shop_user/config"
+	"golang.org/x/time/rate"
+)
+
+// медленное пополнение, чтобы ведро не успело наполниться за время теста
+const testRate = rate.Limit(0.001)
+
+func newTestRateLimiter(t *testing.T, limits RateLimits, store LimiterStore) *RateLimiter {
+	t.Helper()
+	rl := NewRateLimiter(slog.New(slog.DiscardHandler), limits, store)
+	t.Cleanup(rl.Close)
+	return rl
+}
+
+// сколько запросов пропускается подряд, не больше max
+func allowed(t *testing.T, max int, allow func() error) int {
+	t.Helper()
+	for i := range max {
+		err := allow()
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			if rle.RetryAfter <= 0 {
+				t.Errorf("RetryAfter = %s", rle.RetryAfter)
+			}
+			return i
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return max
+}
+
+func TestRateLimiterMethods(t *testing.T) {
+	limits := RateLimits{
+		Default: Limit{Rate: testRate, Burst: 5},
+		Methods: map[string]Limit{
+			user.User_Login_FullMethodName:             {Rate: testRate, Burst: 2},
+			user.User_GetNewAccessToken_FullMethodName: {Rate: testRate, Burst: 3},
+			user.User_GetJWKS_FullMethodName:           {}, // без лимита
+		},
+	}
+	ctx := context.Background()
+	const ip = "203.0.113.7"
+
+	tests := []struct {
+		name   string
+		method string
+		ip     string
+		want   int
+	}{
+		{"method policy", user.User_Login_FullMethodName, ip, 2},
+		{"another method has its own bucket", user.User_GetNewAccessToken_FullMethodName, ip, 3},
+		{"another ip has its own bucket", user.User_Login_FullMethodName, "203.0.113.8", 2},
+		{"disabled limit", user.User_GetJWKS_FullMethodName, ip, 100},
+		{"default policy", user.User_GetUserByID_FullMethodName, ip, 5},
+		// методы без своей политики делят одно ведро
+		{"default bucket is shared", user.User_Register_FullMethodName, ip, 0},
+	}
+	rl := newTestRateLimiter(t, limits, NewMemoryLimiterStore())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allowed(t, 100, func() error { return rl.AllowIP(ctx, tt.method, tt.ip) })
+			if got != tt.want {
+				t.Errorf("allowed %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterPerUser(t *testing.T) {
+	ctx := context.Background()
+	t.Run("bucket per user", func(t *testing.T) {
+		rl := newTestRateLimiter(t, RateLimits{PerUser: Limit{Rate: testRate, Burst: 3}}, NewMemoryLimiterStore())
+		if got := allowed(t, 100, func() error { return rl.AllowUser(ctx, "u1") }); got != 3 {
+			t.Errorf("u1: allowed %d requests, want 3", got)
+		}
+		if got := allowed(t, 100, func() error { return rl.AllowUser(ctx, "u2") }); got != 3 {
+			t.Errorf("u2: allowed %d requests, want 3", got)
+		}
+	})
+
+	t.Run("independent of ip limits", func(t *testing.T) {
+		rl := newTestRateLimiter(t, RateLimits{
+			Default: Limit{Rate: testRate, Burst: 1},
+			PerUser: Limit{Rate: testRate, Burst: 2},
+		}, NewMemoryLimiterStore())
+		if err := rl.AllowIP(ctx, user.User_GetUserByID_FullMethodName, "203.0.113.7"); err != nil {
+			t.Fatal(err)
+		}
+		if got := allowed(t, 100, func() error { return rl.AllowUser(ctx, "u1") }); got != 2 {
+			t.Errorf("allowed %d requests, want 2", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rl := newTestRateLimiter(t, RateLimits{}, NewMemoryLimiterStore())
+		if got := allowed(t, 100, func() error { return rl.AllowUser(ctx, "u1") }); got != 100 {
+			t.Errorf("allowed %d requests, want all", got)
+		}
+	})
+}
+
+// хранилище, которое всегда недоступно, и считает очистки
+type failingStore struct {
+	mu     sync.Mutex
+	cleans int
+}
+
+var errStoreDown = errors.New("connection refused")
+
+func (s *failingStore) TakeToken(ctx context.Context, key string, limit rate.Limit, burst int) (time.Duration, error) {
+	return 0, errStoreDown
+}
+
+func (s *failingStore) DeleteStaleBuckets(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleans++
+	return errStoreDown
+}
+
+func TestRateLimiterStoreError(t *testing.T) {
+	limits := RateLimits{
+		Default: Limit{Rate: testRate, Burst: 5},
+		Methods: map[string]Limit{user.User_Login_FullMethodName: {Rate: testRate, Burst: 2, FailClosed: true}},
+		PerUser: Limit{Rate: testRate, Burst: 5},
+	}
+	rl := newTestRateLimiter(t, limits, &failingStore{})
+	ctx := context.Background()
+	tests := []struct {
+		name           string
+		allow          func() error
+		wantFailClosed bool
+	}{
+		{"fail closed method", func() error { return rl.AllowIP(ctx, user.User_Login_FullMethodName, "203.0.113.7") }, true},
+		{"default policy", func() error { return rl.AllowIP(ctx, user.User_GetUserByID_FullMethodName, "203.0.113.7") }, false},
+		{"per user", func() error { return rl.AllowUser(ctx, "u1") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se *LimiterStoreError
+			if err := tt.allow(); !errors.As(err, &se) {
+				t.Fatalf("%v, want LimiterStoreError", err)
+			}
+			if se.FailClosed != tt.wantFailClosed {
+				t.Errorf("FailClosed = %t", se.FailClosed)
+			}
+			if !errors.Is(se, errStoreDown) {
+				t.Error("store error is not wrapped")
+			}
+		})
+	}
+}
+
+func TestRateLimiterClose(t *testing.T) {
+	store := &failingStore{}
+	rl := NewRateLimiter(slog.New(slog.DiscardHandler), RateLimits{}, store)
+
+	done := make(chan struct{})
+	go func() {
+		// повторный и параллельный Close не паникуют и тоже ждут очистку
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rl.Close()
+			}()
+		}
+		wg.Wait()
+		rl.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close doesn't return")
+	}
+	select {
+	case <-rl.done:
+	default:
+		t.Fatal("cleanup goroutine is still running")
+	}
+	if store.cleans != 0 {
+		t.Errorf("%d cleanups before the first tick", store.cleans)
+	}
+}
+
+func TestIsAllowlisted(t *testing.T) {
+	limits, err := NewRateLimits(config.RateLimit{Allowlist: []string{"10.1.2.3/8", "2001:db8::/32"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := newTestRateLimiter(t, limits, NewMemoryLimiterStore())
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"2001:db8::1", true},
+		{"11.0.0.1", false},
+		{"not an ip", false},
+	}
+	for _, tt := range tests {
+		if got := rl.IsAllowlisted(tt.ip); got != tt.want {
+			t.Errorf("IsAllowlisted(%q) = %t, want %t", tt.ip, got, tt.want)
+		}
+	}
+	if want := netip.MustParsePrefix("10.0.0.0/8"); limits.Allowlist[0] != want {
+		t.Errorf("Allowlist[0] = %s", limits.Allowlist[0])
+	}
+}
+
+// config не импортирует сгенерированный пакет и держит свой список методов
+func TestConfigMethods(t *testing.T) {
+	var want []string
+	for _, m := range user.User_ServiceDesc.Methods {
+		want = append(want, "/"+user.User_ServiceDesc.ServiceName+"/"+m.MethodName)
+	}
+	got := slices.Clone(config.Methods)
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("config.Methods = %v, want %v", got, want)
+	}
+}
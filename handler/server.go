@@ -1,41 +1,88 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
 
 	"github.com/glekoz/online-shop_proto/user"
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/metrics"
 )
 
 type UserService struct {
 	user.UnimplementedUserServer
 	app AppAPI
 
-	logger *slog.Logger
-	rl     *RateLimiter
+	logger   *slog.Logger
+	rl       *RateLimiter
+	health   *Health
+	metrics  *metrics.Metrics
+	services map[string]string // токен : имя сервиса
+	port     int
+	preStop  time.Duration
+	serv     *grpc.Server
 }
 
-func NewServer(app AppAPI, l *slog.Logger) *UserService {
-	return &UserService{app: app, logger: l, rl: NewRateLimiter()}
-}
-
-func (us *UserService) RunServer(port int) error {
-	listen, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// cfg.ServiceTokens - токены сервисов, которым доступны методы для других сервисов, см. ParseServiceTokens.
+// запросы с действительным токеном сервиса не ограничиваются rl.
+// h регистрируется на том же сервере как grpc.health.v1
+func NewServer(app AppAPI, l *slog.Logger, cfg config.GRPC, rl *RateLimiter, h *Health, m *metrics.Metrics) (*UserService, error) {
+	services, err := ParseServiceTokens(string(cfg.ServiceTokens))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	serv := grpc.NewServer(
+	us := &UserService{app: app, logger: l, rl: rl, health: h, metrics: m, services: services, port: cfg.Port, preStop: cfg.PreStopDelay}
+	us.serv = grpc.NewServer(
 		(grpc.ChainUnaryInterceptor(
-			us.RateLimiter,
-			us.TimeCounter,
-			us.RequireAuthInterceptor,
-			us.RequireNoAuthInterceptor,
+			us.RPCMetrics,
+			skipHealth(us.Tracing),
+			skipHealth(traced("RateLimiter", us.RateLimiter)),
+			skipHealth(traced("TimeCounter", us.TimeCounter)),
+			skipHealth(traced("RequireAuth", us.RequireAuthInterceptor)),
+			skipHealth(traced("UserRateLimiter", us.UserRateLimiter)),
+			skipHealth(traced("RequireNoAuth", us.RequireNoAuthInterceptor)),
 			us.PanicRecoverer,
 		)),
 	)
-	user.RegisterUserServer(serv, us)
-	return serv.Serve(listen)
+	user.RegisterUserServer(us.serv, us)
+	h.Register(us.serv)
+	return us, nil
+}
+
+// блокируется до Shutdown, после него возвращает nil
+func (us *UserService) RunServer() error {
+	listen, err := net.Listen("tcp", fmt.Sprintf(":%d", us.port))
+	if err != nil {
+		return err
+	}
+	return us.serv.Serve(listen)
+}
+
+// Shutdown сообщает о неготовности через health и еще preStop принимает запросы,
+// пока балансировщики не заметят NOT_SERVING. затем перестает принимать запросы
+// и ждет завершения начатых. если ctx истекает раньше, оставшиеся запросы обрываются
+func (us *UserService) Shutdown(ctx context.Context) error {
+	us.health.Shutdown()
+	select {
+	case <-time.After(us.preStop):
+	case <-ctx.Done():
+	}
+	done := make(chan struct{})
+	go func() {
+		us.serv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		us.serv.Stop()
+		<-done
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+)
+
+var ErrBadServiceTokens = errors.New("service tokens must be in the form name=token,name=token")
+
+// ParseServiceTokens разбирает строку вида "catalog=token1,comments=token2"
+// в отображение токен : имя сервиса. пустая строка - ни одного сервиса
+func ParseServiceTokens(s string) (map[string]string, error) {
+	res := make(map[string]string)
+	if s == "" {
+		return res, nil
+	}
+	for pair := range strings.SplitSeq(s, ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || token == "" {
+			return nil, ErrBadServiceTokens
+		}
+		if _, exists := res[token]; exists {
+			return nil, ErrBadServiceTokens
+		}
+		res[token] = name
+	}
+	return res, nil
+}
+
+// сравниваются все токены за постоянное время, чтобы по времени ответа
+// нельзя было подобрать токен
+func (us *UserService) serviceByToken(token string) (string, bool) {
+	var service string
+	for t, name := range us.services {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			service = name
+		}
+	}
+	return service, service != ""
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/glekoz/online-shop_user/tracing"
+)
+
+// начинает серверный спан запроса, продолжая трассу вызывающего, если она есть в метаданных.
+// ошибкой отмечаются только сбои сервера, отказы клиенту (неверный пароль, лимиты) - нет
+func (us *UserService) Tracing(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = tracing.Extract(ctx)
+	service, method, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+	ctx, span := tracing.Start(ctx, service+"/"+method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		))
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	return resp, err
+}
+
+// спан на работу одного interceptor. спан заканчивается, когда interceptor передает
+// запрос дальше, поэтому в трассе стадии идут друг за другом, а не вложены
+func traced(name string, interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		parent := trace.SpanFromContext(ctx)
+		sctx, span := tracing.Start(ctx, "interceptor."+name)
+		passed := false
+		resp, err := interceptor(sctx, req, info, func(ctx context.Context, req any) (any, error) {
+			passed = true
+			span.End()
+			// значения, добавленные interceptor, остаются, а родителем снова становится спан запроса
+			return handler(tracing.WithSpan(ctx, parent), req)
+		})
+		if !passed {
+			tracing.End(span, err)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/glekoz/online-shop_proto/user"
+	"github.com/glekoz/online-shop_user/tracing"
+	"github.com/glekoz/online-shop_user/tracing/tracingtest"
+)
+
+var loginInfo = &grpc.UnaryServerInfo{FullMethod: user.User_Login_FullMethodName}
+
+// вызывает interceptors по порядку, как grpc.ChainUnaryInterceptor
+func chain(ctx context.Context, h grpc.UnaryHandler, interceptors ...grpc.UnaryServerInterceptor) (any, error) {
+	if len(interceptors) == 0 {
+		return h(ctx, nil)
+	}
+	return interceptors[0](ctx, nil, loginInfo, func(ctx context.Context, _ any) (any, error) {
+		return chain(ctx, h, interceptors[1:]...)
+	})
+}
+
+func pass(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+	return h(ctx, req)
+}
+
+// обработчик со своим спаном, как методы App
+func appHandler(err error) grpc.UnaryHandler {
+	return func(ctx context.Context, _ any) (any, error) {
+		_, span := tracing.Start(ctx, "App.Login")
+		tracing.End(span, err)
+		return nil, err
+	}
+}
+
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no span %q", name)
+	return tracetest.SpanStub{}
+}
+
+func intAttr(s tracetest.SpanStub, key attribute.Key) int64 {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.AsInt64()
+		}
+	}
+	return -1
+}
+
+func TestTracingInterceptors(t *testing.T) {
+	us := &UserService{}
+
+	t.Run("stages follow each other", func(t *testing.T) {
+		exp := tracingtest.InMemory()
+		_, err := chain(context.Background(), appHandler(nil),
+			us.Tracing, traced("RateLimiter", pass), traced("RequireNoAuth", pass))
+		if err != nil {
+			t.Fatal(err)
+		}
+		spans := exp.GetSpans()
+		if len(spans) != 4 {
+			t.Fatalf("got %d spans, want 4", len(spans))
+		}
+		server := spanByName(t, spans, "User/Login")
+		limiter := spanByName(t, spans, "interceptor.RateLimiter")
+		noAuth := spanByName(t, spans, "interceptor.RequireNoAuth")
+		app := spanByName(t, spans, "App.Login")
+		for _, s := range []tracetest.SpanStub{limiter, noAuth, app} {
+			if s.Parent.SpanID() != server.SpanContext.SpanID() {
+				t.Errorf("%s is not a child of the request span", s.Name)
+			}
+		}
+		if limiter.EndTime.After(noAuth.StartTime) || noAuth.EndTime.After(app.StartTime) {
+			t.Error("interceptor span is still open when the next stage starts")
+		}
+		if server.Status.Code == otelcodes.Error {
+			t.Error("successful request is marked as error")
+		}
+	})
+
+	t.Run("rejected by interceptor", func(t *testing.T) {
+		exp := tracingtest.InMemory()
+		limited := status.Error(codes.ResourceExhausted, "too many requests")
+		reject := func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+			return nil, limited
+		}
+		_, err := chain(context.Background(), appHandler(nil), us.Tracing, traced("RateLimiter", reject))
+		if !errors.Is(err, limited) {
+			t.Fatalf("got %v", err)
+		}
+		spans := exp.GetSpans()
+		if len(spans) != 2 {
+			t.Fatalf("got %d spans, want the request and the interceptor", len(spans))
+		}
+		if s := spanByName(t, spans, "interceptor.RateLimiter"); s.Status.Code != otelcodes.Error {
+			t.Error("rejecting interceptor span has no error")
+		}
+		server := spanByName(t, spans, "User/Login")
+		// отказ клиенту - не сбой сервера
+		if server.Status.Code == otelcodes.Error {
+			t.Error("client error is marked as server failure")
+		}
+		if got := intAttr(server, "rpc.grpc.status_code"); got != int64(codes.ResourceExhausted) {
+			t.Errorf("status code attribute %d", got)
+		}
+	})
+
+	t.Run("server failure", func(t *testing.T) {
+		exp := tracingtest.InMemory()
+		_, _ = chain(context.Background(), appHandler(status.Error(codes.Internal, "internal error")), us.Tracing)
+		if s := spanByName(t, exp.GetSpans(), "User/Login"); s.Status.Code != otelcodes.Error {
+			t.Error("internal error is not marked on the request span")
+		}
+	})
+
+	t.Run("caller trace continues", func(t *testing.T) {
+		exp := tracingtest.InMemory()
+		const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("traceparent", "00-"+traceID+"-"+parentID+"-01"))
+		if _, err := chain(ctx, appHandler(nil), us.Tracing); err != nil {
+			t.Fatal(err)
+		}
+		server := spanByName(t, exp.GetSpans(), "User/Login")
+		if server.SpanContext.TraceID().String() != traceID || server.Parent.SpanID().String() != parentID {
+			t.Errorf("request span %s, parent %s", server.SpanContext.TraceID(), server.Parent.SpanID())
+		}
+		if !server.Parent.IsRemote() {
+			t.Error("parent is not remote")
+		}
+	})
+}
@@ -0,0 +1,12 @@
+package keys
+
+import "errors"
+
+var (
+	ErrNoKeys          = errors.New("no keys provided")
+	ErrBadPEM          = errors.New("file does not contain a PEM encoded private key")
+	ErrNotRSA          = errors.New("private key is not an RSA key")
+	ErrBadPeriod       = errors.New("rotation period and overlap must be positive")
+	ErrStaticKeys      = errors.New("keys loaded from files can't be rotated")
+	ErrBadPublishDelay = errors.New("publish delay must be at least the reload interval and less than the rotation period")
+)
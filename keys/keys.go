@@ -0,0 +1,249 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const keySize = 2048
+
+// как часто Run перечитывает директорию ключей
+const reloadInterval = time.Minute
+
+type Key struct {
+	ID        string // kid, отпечаток публичного ключа по RFC 7638
+	Private   *rsa.PrivateKey
+	CreatedAt time.Time
+
+	path string
+}
+
+type PublicKey struct {
+	ID  string
+	Key *rsa.PublicKey
+}
+
+// Manager хранит ключи подписи токенов.
+// Новый ключ сначала только публикуется в JWKS и начинает подписывать через publishDelay,
+// когда его уже подхватили другие реплики и кэши JWKS потребителей.
+// Токены подписываются самым новым опубликованным ключом, а старые ключи остаются
+// доступными для проверки ещё overlap после того, как преемник начал подписывать,
+// чтобы выпущенные ими токены (в том числе рефреш) успели истечь.
+type Manager struct {
+	mu   sync.RWMutex
+	keys []*Key // отсортированы по CreatedAt
+
+	dir          string // пусто, если ключи заданы файлами и ротация невозможна
+	rotateEvery  time.Duration
+	overlap      time.Duration
+	publishDelay time.Duration
+	logger       *slog.Logger
+}
+
+// FromFiles загружает фиксированный набор ключей, ротация не выполняется.
+// Подписывает первый файл, остальные нужны только для проверки токенов,
+// выпущенных до ручной смены ключа
+func FromFiles(paths ...string) (*Manager, error) {
+	if len(paths) == 0 {
+		return nil, ErrNoKeys
+	}
+	keys := make([]*Key, 0, len(paths))
+	for _, p := range paths {
+		k, err := readKey(p)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	slices.Reverse(keys)
+	return &Manager{keys: keys}, nil
+}
+
+// FromDir загружает все *.pem из директории и при необходимости создает первый ключ.
+// Новые ключи сохраняются в ту же директорию, поэтому переживают перезапуск.
+// publishDelay не может быть меньше интервала перечитывания директории, иначе
+// реплика начнет подписывать ключом, которого другие реплики еще не знают
+func FromDir(dir string, rotateEvery, overlap, publishDelay time.Duration, logger *slog.Logger) (*Manager, error) {
+	if rotateEvery <= 0 || overlap <= 0 {
+		return nil, ErrBadPeriod
+	}
+	if publishDelay < reloadInterval || publishDelay >= rotateEvery {
+		return nil, ErrBadPublishDelay
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		dir:          dir,
+		rotateEvery:  rotateEvery,
+		overlap:      overlap,
+		publishDelay: publishDelay,
+		logger:       logger,
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if len(m.keys) == 0 {
+		if _, err := m.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Manager) SigningKey() (string, *rsa.PrivateKey) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	k := m.keys[m.signingIndex(time.Now())]
+	return k.ID, k.Private
+}
+
+// самый новый ключ, который опубликован не меньше publishDelay.
+// если таких нет (первый запуск с пустой директорией), подписывает самый старый:
+// токенов еще нет, и проверять их другим ключом некому
+func (m *Manager) signingIndex(now time.Time) int {
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if !m.signsFrom(m.keys[i]).After(now) {
+			return i
+		}
+	}
+	return 0
+}
+
+// время, с которого ключ подписывает токены
+func (m *Manager) signsFrom(k *Key) time.Time {
+	if m.dir == "" {
+		// набор ключей из файлов фиксирован, публиковать заранее нечего
+		return time.Time{}
+	}
+	return k.CreatedAt.Add(m.publishDelay)
+}
+
+func (m *Manager) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.keys {
+		if k.ID == kid {
+			return &k.Private.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// для проверки готовности: без ключа подписи сервис не может выдавать токены
+func (m *Manager) Check(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 {
+		return ErrNoKeys
+	}
+	return nil
+}
+
+// все ключи, которыми сейчас можно проверить токен, начиная с самого нового.
+// сюда входит и ключ, который еще не подписывает, чтобы потребители узнали о нем заранее
+func (m *Manager) PublicKeys() []PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make([]PublicKey, 0, len(m.keys))
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		res = append(res, PublicKey{ID: m.keys[i].ID, Key: &m.keys[i].Private.PublicKey})
+	}
+	return res
+}
+
+// Rotate создает и публикует новый ключ, подписывать он начнет через publishDelay.
+// предыдущие продолжают проверять токены
+func (m *Manager) Rotate() (string, error) {
+	if m.dir == "" {
+		return "", ErrStaticKeys
+	}
+	private, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return "", err
+	}
+	k, err := writeKey(m.dir, private)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.keys = append(m.keys, k)
+	m.mu.Unlock()
+	return k.ID, nil
+}
+
+// Run раз в reloadInterval подхватывает ключи, созданные другими репликами,
+// ротирует ключ подписи по расписанию и удаляет ключи, у которых истекло перекрытие.
+// Работает, пока не отменен ctx
+func (m *Manager) Run(ctx context.Context) {
+	if m.dir == "" {
+		return
+	}
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.reload(); err != nil {
+			m.logger.ErrorContext(ctx, "keys reload failed", "error", err.Error())
+			continue
+		}
+		m.mu.RLock()
+		newest := m.keys[len(m.keys)-1].CreatedAt
+		m.mu.RUnlock()
+		if time.Since(newest) >= m.rotateEvery {
+			kid, err := m.Rotate()
+			if err != nil {
+				m.logger.ErrorContext(ctx, "key rotation failed", "error", err.Error())
+				continue
+			}
+			m.logger.InfoContext(ctx, "new signing key published", "kid", kid, "signs_after", m.publishDelay.String())
+		}
+		m.prune(ctx)
+	}
+}
+
+// ключ перестает быть нужен через overlap после того, как преемник начал подписывать
+func (m *Manager) prune(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keep := m.keys[:0]
+	for i, k := range m.keys {
+		if i < len(m.keys)-1 && time.Since(m.signsFrom(m.keys[i+1])) > m.overlap {
+			if err := removeKey(k); err != nil {
+				m.logger.ErrorContext(ctx, "expired key removal failed", "kid", k.ID, "error", err.Error())
+			} else {
+				m.logger.InfoContext(ctx, "expired key removed", "kid", k.ID)
+			}
+			continue
+		}
+		keep = append(keep, k)
+	}
+	m.keys = keep
+}
+
+func (m *Manager) reload() error {
+	keys, err := readDir(m.dir)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(keys, func(a, b *Key) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	m.mu.Lock()
+	// пока директория пуста, FromDir сам создаст ключ
+	if len(keys) > 0 || len(m.keys) == 0 {
+		m.keys = keys
+	}
+	m.mu.Unlock()
+	return nil
+}
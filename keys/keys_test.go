@@ -0,0 +1,173 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testRotateEvery  = 30 * 24 * time.Hour
+	testOverlap      = 48 * time.Hour
+	testPublishDelay = 15 * time.Minute
+)
+
+func newTestManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+	m, err := FromDir(dir, testRotateEvery, testOverlap, testPublishDelay, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func published(m *Manager, kid string) bool {
+	return slices.ContainsFunc(m.PublicKeys(), func(k PublicKey) bool { return k.ID == kid })
+}
+
+func (m *Manager) signingKID(now time.Time) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[m.signingIndex(now)].ID
+}
+
+func TestRotatePublishesBeforeSigning(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	first, _ := m.SigningKey()
+
+	next, err := m.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !published(m, next) {
+		t.Fatal("rotated key is not in JWKS")
+	}
+	if kid, _ := m.SigningKey(); kid != first {
+		t.Fatal("rotated key signs before publish delay")
+	}
+	if _, ok := m.PublicKey(next); !ok {
+		t.Error("rotated key can't verify tokens")
+	}
+
+	// другая реплика подхватывает ключ из директории и тоже ждет publishDelay,
+	// а не начинает подписывать по времени изменения файла
+	replica := newTestManager(t, dir)
+	if !published(replica, next) {
+		t.Fatal("replica doesn't publish the rotated key")
+	}
+	created := time.Now()
+	for _, d := range []time.Duration{0, testPublishDelay - time.Second} {
+		for _, mm := range []*Manager{m, replica} {
+			if kid := mm.signingKID(created.Add(d)); kid != first {
+				t.Errorf("after %s signs with %s, want the old key", d, kid)
+			}
+		}
+	}
+	for _, mm := range []*Manager{m, replica} {
+		if kid := mm.signingKID(created.Add(testPublishDelay + time.Second)); kid != next {
+			t.Errorf("after publish delay signs with %s, want the rotated key", kid)
+		}
+	}
+}
+
+func TestCreatedAtSurvivesCopy(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	kid, err := m.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want time.Time
+	for _, k := range m.keys {
+		if k.ID == kid {
+			want = k.CreatedAt
+		}
+	}
+
+	// копирование и восстановление из бэкапа меняют mtime
+	path := filepath.Join(dir, kid+".pem")
+	old := time.Now().Add(-365 * 24 * time.Hour)
+	if err = os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	k, err := readKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %s, want %s", k.CreatedAt, want)
+	}
+	if kid := newTestManager(t, dir).signingKID(time.Now()); kid == k.ID {
+		t.Error("key with old mtime signs right after reload")
+	}
+}
+
+func TestReadKeyWithoutHeader(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "manual.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err = os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	k, err := readKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.CreatedAt.Equal(mtime) {
+		t.Errorf("CreatedAt = %s, want file mtime %s", k.CreatedAt, mtime)
+	}
+	if k.ID != Thumbprint(&private.PublicKey) {
+		t.Error("kid is not the key thumbprint")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+	first, _ := m.SigningKey()
+	next, err := m.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// преемник подписывает дольше overlap
+	m.keys[1].CreatedAt = time.Now().Add(-testPublishDelay - testOverlap - time.Minute)
+	m.prune(t.Context())
+	if published(m, first) {
+		t.Error("expired key is still published")
+	}
+	if !published(m, next) {
+		t.Error("signing key is removed")
+	}
+	if _, err = os.Stat(filepath.Join(m.dir, first+".pem")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expired key file: %v", err)
+	}
+}
+
+func TestFromDirValidation(t *testing.T) {
+	dir := t.TempDir()
+	l := slog.New(slog.DiscardHandler)
+	if _, err := FromDir(dir, testRotateEvery, testOverlap, reloadInterval-time.Second, l); !errors.Is(err, ErrBadPublishDelay) {
+		t.Errorf("short publish delay: %v", err)
+	}
+	if _, err := FromDir(dir, testRotateEvery, testOverlap, testRotateEvery, l); !errors.Is(err, ErrBadPublishDelay) {
+		t.Errorf("publish delay equal to rotation period: %v", err)
+	}
+	if _, err := FromDir(dir, 0, testOverlap, testPublishDelay, l); !errors.Is(err, ErrBadPeriod) {
+		t.Errorf("zero rotation period: %v", err)
+	}
+}
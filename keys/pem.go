@@ -0,0 +1,153 @@
+package keys
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func readDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(paths))
+	for _, p := range paths {
+		k, err := readKey(p)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// заголовок PEM с временем создания ключа. mtime для этого не годится:
+// его меняют копирование, восстановление из бэкапа и синхронизация тома
+const createdHeader = "Created"
+
+// принимает приватный ключ в PKCS #1 ("RSA PRIVATE KEY") или PKCS #8 ("PRIVATE KEY").
+// время создания берется из заголовка Created, у ключей без него (созданных вручную) -
+// время изменения файла
+func readKey(path string) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadPEM, path)
+	}
+	var private *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var k any
+		k, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if private, ok = k.(*rsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotRSA, path)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrBadPEM, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	createdAt, err := keyCreatedAt(path, block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:        Thumbprint(&private.PublicKey),
+		Private:   private,
+		CreatedAt: createdAt,
+		path:      path,
+	}, nil
+}
+
+func keyCreatedAt(path string, block *pem.Block) (time.Time, error) {
+	if v, ok := block.Headers[createdHeader]; ok {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s: %w", ErrBadPEM, path, err)
+		}
+		return t, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// файл пишется во временный и переименовывается, чтобы другие реплики
+// не прочитали его наполовину записанным
+func writeKey(dir string, private *rsa.PrivateKey) (*Key, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	kid := Thumbprint(&private.PublicKey)
+	createdAt := time.Now()
+	path := filepath.Join(dir, kid+".pem")
+	tmp, err := os.CreateTemp(dir, kid+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{createdHeader: createdAt.UTC().Format(time.RFC3339Nano)},
+		Bytes:   der,
+	}
+	if err = pem.Encode(tmp, block); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return &Key{ID: kid, Private: private, CreatedAt: createdAt, path: path}, nil
+}
+
+func removeKey(k *Key) error {
+	if k.path == "" {
+		return nil
+	}
+	err := os.Remove(k.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Thumbprint - отпечаток ключа по RFC 7638, используется как kid.
+// Он одинаков на всех репликах и не меняется при перезапуске
+func Thumbprint(pub *rsa.PublicKey) string {
+	n, e := Components(pub)
+	// поля в лексикографическом порядке и без пробелов, как требует RFC
+	jwk := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, e, n)
+	sum := sha256.Sum256([]byte(jwk))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// модуль и экспонента в base64url без паддинга, как в JWK
+func Components(pub *rsa.PublicKey) (n, e string) {
+	n = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+	e = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	return n, e
+}
@@ -2,79 +2,252 @@ package mail
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"os"
-	"strings"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
 	"time"
 
-	"github.com/mailgun/mailgun-go/v5"
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/repository"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type CacheAPI interface {
-	Add(userID, token string) error
-	Get(userID string) (string, bool)
-	Delete(userID string)
+// очередь писем и токены из них, см. repository.EnqueueMail и repository.EnqueueTokenMail.
+// токены хранятся в БД, а не в памяти процесса, иначе ссылка из письма, отправленного
+// после перезапуска или другой репликой, не проходила бы проверку
+type OutboxAPI interface {
+	EnqueueMail(ctx context.Context, m models.OutboxMail) (int64, error)
+	EnqueueTokenMail(ctx context.Context, t models.MailToken, m models.OutboxMail) (int64, error)
+	MailTokenValid(ctx context.Context, key, hash string) (bool, error)
+	UseMailToken(ctx context.Context, key, hash string) (bool, error)
+	DeleteMailToken(ctx context.Context, key string) error
 }
 
-type Mail struct {
-	mg    *mailgun.Client
-	table CacheAPI
+// на запросы к БД, методы Mail вызываются без контекста
+const storeTimeout = 10 * time.Second
 
-	domain string
-	sender string
+// Send* методы не отправляют письма, а ставят их в очередь, откуда
+// их забирает app.RunMailOutbox и отправляет через Deliver
+type Mail struct {
+	sender    Sender
+	transport string // для трассировки
+	templates *Templates
+	outbox    OutboxAPI
+	tokenTTL  time.Duration
 }
 
-func New(c CacheAPI) *Mail {
-	b, err := os.ReadFile("env.env")
+// ошибки в настройках транспорта и в шаблонах обнаруживаются здесь, при запуске.
+// tokenTTL - время жизни токенов из писем
+func New(cfg config.Mail, tokenTTL time.Duration, outbox OutboxAPI) (*Mail, error) {
+	sender, err := NewSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := NewTemplates(cfg.Branding)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	apiKey := strings.TrimSpace(string(b[len("MAIL_KEY="):]))
-	mg := mailgun.NewMailgun(apiKey)
 	return &Mail{
-		mg:    mg,
-		table: c,
-		// вынести в конфиг
-		domain: "sandbox9628d10d1ee1475ab56628fba22071c1.mailgun.org",
-		sender: "User Service <postmaster@sandbox9628d10d1ee1475ab56628fba22071c1.mailgun.org>",
+		sender:    sender,
+		transport: cfg.Transport,
+		templates: templates,
+		outbox:    outbox,
+		tokenTTL:  tokenTTL,
+	}, nil
+}
+
+// locale - язык пользователя, см. models.Locales
+func newOutboxMail(locale, template, recipient string, data TemplateData) (models.OutboxMail, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return models.OutboxMail{}, err
 	}
+	return models.OutboxMail{Template: template, Locale: locale, To: recipient, Data: b}, nil
 }
 
-func (m *Mail) sendMessage(subject, recipient, message string) (string, error) {
-	msg := mailgun.NewMessage(m.domain, m.sender, subject, message, recipient)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+// возвращает id письма в очереди
+func (m *Mail) sendMessage(locale, template, recipient string, data TemplateData) (string, error) {
+	om, err := newOutboxMail(locale, template, recipient, data)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
 	defer cancel()
-	resp, err := m.mg.Send(ctx, msg)
+	id, err := m.outbox.EnqueueMail(ctx, om)
 	if err != nil {
 		return "", err
 	}
-	return resp.ID, nil
+	return strconv.FormatInt(id, 10), nil
 }
 
-func (m *Mail) SendEmailConfirmationMessage(userID, email string, mailtoken, link string) (string, error) {
-	if _, ok := m.table.Get(userID); ok {
-		// чтобы не было возможности израскодовать квоту писем
-		return "", ErrMsgAlreadySent
+// отправляет письмо из очереди, возвращает id письма у провайдера
+func (m *Mail) Deliver(ctx context.Context, om models.OutboxMail) (id string, err error) {
+	ctx, span := tracing.Start(ctx, "Mail.Deliver", trace.WithAttributes(
+		attribute.String("mail.template", om.Template),
+		attribute.String("mail.locale", om.Locale),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	var data TemplateData
+	if err := json.Unmarshal(om.Data, &data); err != nil {
+		return "", err
 	}
-	err := m.table.Add(userID, mailtoken)
+	msg, err := m.templates.Render(om.Locale, om.Template, data)
 	if err != nil {
 		return "", err
 	}
-	msg := fmt.Sprintf("You need to confirm your email through this link: %s", link)
-	msgID, err := m.sendMessage("Email Confirmation", email, msg)
+	msg.To = om.To
+	return m.send(ctx, msg)
+}
+
+// отдельный спан, чтобы время провайдера было видно отдельно от шаблонов
+func (m *Mail) send(ctx context.Context, msg Message) (id string, err error) {
+	ctx, span := tracing.Start(ctx, "Mail.Send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("mail.transport", m.transport)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	return m.sender.Send(ctx, msg)
+}
+
+// для проверки готовности. отправители для разработки всегда доступны
+func (m *Mail) Check(ctx context.Context) error {
+	if c, ok := m.sender.(Checker); ok {
+		return c.Check(ctx)
+	}
+	return nil
+}
+
+// письмо для регистрации и его токен, которые пишутся в БД вместе с пользователем,
+// см. repository.CreateUser
+func (m *Mail) EmailConfirmationMail(locale, userID, email string, mailtoken, link string) (models.MailToken, models.OutboxMail, error) {
+	om, err := newOutboxMail(locale, TemplateConfirmation, email, TemplateData{Link: link})
+	if err != nil {
+		return models.MailToken{}, models.OutboxMail{}, err
+	}
+	return m.newToken(userID, userID, mailtoken), om, nil
+}
+
+func (m *Mail) SendEmailConfirmationMessage(locale, userID, email string, mailtoken, link string) (string, error) {
+	return m.sendTokenMessage(userID, userID, mailtoken, locale, TemplateConfirmation, email, TemplateData{Link: link})
+}
+
+func (m *Mail) CheckToken(userID, token string) (bool, error) {
+	return m.useToken(userID, token)
+}
+
+// токены сброса пароля лежат в той же таблице, но под своим ключом,
+// чтобы не мешать подтверждению почты
+func resetKey(userID string) string {
+	return "reset:" + userID
+}
+
+func (m *Mail) SendPasswordResetMessage(locale, userID, email string, resettoken, link string) (string, error) {
+	return m.sendTokenMessage(userID, resetKey(userID), resettoken, locale, TemplatePasswordReset, email, TemplateData{Link: link})
+}
+
+// токен сброса не удаляется при проверке: пароль еще может не пройти проверку
+// истории или не сохраниться, тогда ссылка из письма должна остаться рабочей.
+// после смены пароля токен удаляется через DeleteResetToken
+func (m *Mail) ValidResetToken(userID, token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return m.outbox.MailTokenValid(ctx, resetKey(userID), hashToken(token))
+}
+
+func (m *Mail) DeleteResetToken(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return m.outbox.DeleteMailToken(ctx, resetKey(userID))
+}
+
+func unlockKey(userID string) string {
+	return "unlock:" + userID
+}
+
+func (m *Mail) SendAccountUnlockMessage(locale, userID, email string, unlocktoken, link string) (string, error) {
+	data := TemplateData{Link: link, Event: AlertAccountLocked}
+	return m.sendTokenMessage(userID, unlockKey(userID), unlocktoken, locale, TemplateSecurityAlert, email, data)
+}
+
+func (m *Mail) CheckUnlockToken(userID, token string) (bool, error) {
+	return m.useToken(unlockKey(userID), token)
+}
+
+// ключ включает новую почту, чтобы ссылка подтверждала именно тот адрес,
+// на который пришла
+func emailChangeKey(userID, email string) string {
+	return "email_change:" + userID + ":" + email
+}
+
+func (m *Mail) SendEmailChangeMessage(locale, userID, newEmail string, mailtoken, link string) (string, error) {
+	data := TemplateData{Link: link, NewEmail: newEmail}
+	return m.sendTokenMessage(userID, emailChangeKey(userID, newEmail), mailtoken, locale, TemplateEmailChange, newEmail, data)
+}
+
+func (m *Mail) CheckEmailChangeToken(userID, newEmail, token string) (bool, error) {
+	return m.useToken(emailChangeKey(userID, newEmail), token)
+}
+
+// уведомление на старую почту, чтобы владелец узнал о попытке смены
+func (m *Mail) SendEmailChangeNotice(locale, oldEmail, newEmail string) (string, error) {
+	data := TemplateData{NewEmail: newEmail, Event: AlertEmailChangeRequested}
+	return m.sendMessage(locale, TemplateSecurityAlert, oldEmail, data)
+}
+
+func (m *Mail) SendAccountDeletionMessage(locale, email string, at time.Time) (string, error) {
+	return m.sendMessage(locale, TemplateAccountDeletion, email, TemplateData{At: at})
+}
+
+// уведомление на случай, если пароль сменил не сам пользователь
+func (m *Mail) SendPasswordChangedMessage(locale, email string) (string, error) {
+	return m.sendMessage(locale, TemplateSecurityAlert, email, TemplateData{Event: AlertPasswordChanged})
+}
+
+func (m *Mail) sendTokenMessage(userID, key, token, locale, template, email string, data TemplateData) (string, error) {
+	om, err := newOutboxMail(locale, template, email, data)
 	if err != nil {
-		m.table.Delete(userID)
 		return "", err
 	}
-	return msgID, nil
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	// токен и письмо пишутся вместе, поэтому при ошибке не остается ни того, ни другого.
+	// ошибки доставки повторяются воркерами и токен не трогают
+	id, err := m.outbox.EnqueueTokenMail(ctx, m.newToken(userID, key, token), om)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			// чтобы не было возможности израскодовать квоту писем
+			return "", ErrMsgAlreadySent
+		}
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
 }
 
-func (m *Mail) CheckToken(userID, token string) bool {
-	mtoken, ok := m.table.Get(userID)
-	if !ok || mtoken != token {
-		return false
+func (m *Mail) newToken(userID, key, token string) models.MailToken {
+	return models.MailToken{
+		Key:       key,
+		UserID:    userID,
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(m.tokenTTL),
 	}
-	m.table.Delete(userID)
-	return true
+}
+
+// токен одноразовый, поэтому при успешной проверке удаляется
+func (m *Mail) useToken(key, token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return m.outbox.UseMailToken(ctx, key, hashToken(token))
+}
+
+// в БД только хэш, чтобы по ее копии нельзя было перейти по ссылкам из писем
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+
+	"github.com/mailgun/mailgun-go/v5"
+)
+
+type MailgunSender struct {
+	mg     *mailgun.Client
+	domain string
+	from   string
+}
+
+func NewMailgunSender(domain, apiKey string, from *mail.Address) (*MailgunSender, error) {
+	if domain == "" || apiKey == "" {
+		return nil, errors.New("mailgun domain and api key are required")
+	}
+	return &MailgunSender{
+		mg:     mailgun.NewMailgun(apiKey),
+		domain: domain,
+		from:   from.String(),
+	}, nil
+}
+
+func (s *MailgunSender) Send(ctx context.Context, msg Message) (string, error) {
+	m := mailgun.NewMessage(s.domain, s.from, msg.Subject, msg.Text, msg.To)
+	if msg.HTML != "" {
+		m.SetHTML(msg.HTML)
+	}
+	resp, err := s.mg.Send(ctx, m)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// запрос информации о домене проверяет и доступность API, и ключ
+func (s *MailgunSender) Check(ctx context.Context) error {
+	_, err := s.mg.GetDomain(ctx, s.domain, nil)
+	return err
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"net/mail"
+	"os"
+	"sync"
+)
+
+// пишет письма целиком вместо отправки, чтобы локально переходить по ссылкам из них
+type OutboxSender struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from *mail.Address
+}
+
+func NewOutboxSender(w io.Writer, from *mail.Address) *OutboxSender {
+	return &OutboxSender{w: w, from: from}
+}
+
+// файл открывается на дозапись и не закрывается до конца работы процесса
+func NewFileSender(path string, from *mail.Address) (*OutboxSender, error) {
+	if path == "" {
+		path = "outbox.eml"
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewOutboxSender(f, from), nil
+}
+
+func (s *OutboxSender) Send(ctx context.Context, msg Message) (string, error) {
+	id := newMessageID(s.from)
+	body, err := msg.bytes(s.from, id, false)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(body, "\r\n"...)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/google/uuid"
+)
+
+var ErrUnknownTransport = errors.New("unknown mail transport")
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string // пустой - письмо только с текстом
+}
+
+// способ доставки писем, возвращает id отправленного письма
+type Sender interface {
+	Send(ctx context.Context, msg Message) (string, error)
+}
+
+// отправитель, доступность которого можно проверить без отправки письма
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+const (
+	TransportMailgun = "mailgun"
+	TransportSMTP    = "smtp"
+	TransportFile    = "file"   // письма дописываются в OutboxPath, для разработки
+	TransportStdout  = "stdout" // письма печатаются в stdout, для разработки
+)
+
+func NewSender(cfg config.Mail) (Sender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("bad sender address %q: %w", cfg.From, err)
+	}
+	switch cfg.Transport {
+	case TransportMailgun:
+		return NewMailgunSender(cfg.Mailgun.Domain, string(cfg.Mailgun.APIKey), from)
+	case TransportSMTP:
+		s := cfg.SMTP
+		return NewSMTPSender(s.Host, s.Port, s.Username, string(s.Password), s.StartTLS, from)
+	case TransportFile:
+		return NewFileSender(cfg.OutboxPath, from)
+	case TransportStdout:
+		return NewOutboxSender(os.Stdout, from), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, cfg.Transport)
+	}
+}
+
+// id в формате заголовка Message-ID, домен берется из адреса отправителя
+func newMessageID(from *mail.Address) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from.Address, '@'); i >= 0 {
+		domain = from.Address[i+1:]
+	}
+	return "<" + uuid.NewString() + "@" + domain + ">"
+}
+
+// письмо в формате RFC 5322 для SMTP и файлового outbox, с HTML версией -
+// multipart/alternative. в outbox текст пишется как есть (8bit), чтобы ссылки
+// в нем оставались читаемыми
+func (msg Message) bytes(from *mail.Address, id string, quoted bool) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", id)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" {
+		h := partHeader("text/plain", quoted)
+		fmt.Fprintf(&b, "Content-Type: %s\r\n", h.Get("Content-Type"))
+		fmt.Fprintf(&b, "Content-Transfer-Encoding: %s\r\n\r\n", h.Get("Content-Transfer-Encoding"))
+		if err := writeBody(&b, msg.Text, quoted); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	// клиенты показывают последнюю часть, которую умеют отображать, поэтому HTML идет второй
+	for _, part := range []struct{ typ, body string }{{"text/plain", msg.Text}, {"text/html", msg.HTML}} {
+		w, err := mw.CreatePart(partHeader(part.typ, quoted))
+		if err != nil {
+			return nil, err
+		}
+		if err = writeBody(w, part.body, quoted); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func partHeader(typ string, quoted bool) textproto.MIMEHeader {
+	encoding := "8bit"
+	if quoted {
+		encoding = "quoted-printable"
+	}
+	return textproto.MIMEHeader{
+		"Content-Type":              {typ + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {encoding},
+	}
+}
+
+func writeBody(w io.Writer, body string, quoted bool) error {
+	if !quoted {
+		_, err := io.WriteString(w, body+"\r\n")
+		return err
+	}
+	qw := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qw, body); err != nil {
+		return err
+	}
+	if err := qw.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+type SMTPSender struct {
+	host     string
+	addr     string
+	username string
+	password string
+	startTLS bool
+	from     *mail.Address
+}
+
+// без startTLS пароль уйдет открытым текстом, поэтому net/smtp откажется
+// аутентифицироваться не на localhost
+func NewSMTPSender(host string, port int, username, password string, startTLS bool, from *mail.Address) (*SMTPSender, error) {
+	if host == "" || port <= 0 {
+		return nil, errors.New("smtp host and port are required")
+	}
+	return &SMTPSender{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: username,
+		password: password,
+		startTLS: startTLS,
+		from:     from,
+	}, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) (string, error) {
+	id := newMessageID(s.from)
+	body, err := msg.bytes(s.from, id, true)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return "", err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return "", err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return id, c.Quit()
+}
+
+// соединяется и аутентифицируется так же, как при отправке, но письмо не передает
+func (s *SMTPSender) Check(ctx context.Context) error {
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Quit()
+}
+
+// соединение после STARTTLS и аутентификации
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	// net/smtp не принимает контекст, поэтому он ограничивает соединение дедлайном
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.startTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
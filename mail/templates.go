@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+// Шаблоны писем лежат в templates:
+//
+//	layout.html.tmpl, layout.txt.tmpl - общая разметка с брендингом, одна на все языки
+//	<locale>/footer.tmpl              - подпись письма, шаблон "footer"
+//	<locale>/<name>.txt.tmpl          - шаблоны "subject" и "content" текстовой версии
+//	<locale>/<name>.html.tmpl         - шаблон "content" HTML версии
+//
+// Новый язык добавляется каталогом с теми же файлами и значением в models.Locales.
+//
+//go:embed templates
+var templatesFS embed.FS
+
+const (
+	TemplateConfirmation    = "confirmation"
+	TemplatePasswordReset   = "password_reset"
+	TemplateEmailChange     = "email_change"
+	TemplateSecurityAlert   = "security_alert"
+	TemplateAccountDeletion = "account_deletion"
+)
+
+var TemplateNames = []string{
+	TemplateConfirmation,
+	TemplatePasswordReset,
+	TemplateEmailChange,
+	TemplateSecurityAlert,
+	TemplateAccountDeletion,
+}
+
+// события для TemplateSecurityAlert
+const (
+	AlertPasswordChanged      = "password_changed"
+	AlertEmailChangeRequested = "email_change_requested"
+	AlertAccountLocked        = "account_locked" // со ссылкой на разблокировку
+)
+
+type TemplateData struct {
+	Brand    config.Branding // переменные магазина, заполняются при рендеринге
+	Link     string
+	NewEmail string
+	At       time.Time
+	Event    string // для TemplateSecurityAlert
+}
+
+type Templates struct {
+	brand config.Branding
+	text  map[string]*texttemplate.Template // ключ - templateKey
+	html  map[string]*htmltemplate.Template
+}
+
+func templateKey(locale, name string) string {
+	return locale + "/" + name
+}
+
+// даты в письмах всегда в UTC, потому что часовой пояс пользователя неизвестен
+var dateLayouts = map[string]string{
+	models.LocaleRU: "02.01.2006 15:04 MST",
+	models.LocaleEN: "January 2, 2006 15:04 MST",
+}
+
+// разбирает все шаблоны сразу, чтобы ошибка в них обнаруживалась при запуске
+func NewTemplates(brand config.Branding) (*Templates, error) {
+	t := &Templates{
+		brand: brand,
+		text:  make(map[string]*texttemplate.Template),
+		html:  make(map[string]*htmltemplate.Template),
+	}
+	for _, locale := range models.Locales {
+		layout, ok := dateLayouts[locale]
+		if !ok {
+			return nil, fmt.Errorf("no date layout for locale %s", locale)
+		}
+		date := func(at time.Time) string {
+			return at.UTC().Format(layout)
+		}
+		footer := "templates/" + locale + "/footer.tmpl"
+		for _, name := range TemplateNames {
+			base := "templates/" + locale + "/" + name
+			txt, err := texttemplate.New("").
+				Funcs(texttemplate.FuncMap{"date": date}).
+				ParseFS(templatesFS, "templates/layout.txt.tmpl", footer, base+".txt.tmpl")
+			if err != nil {
+				return nil, err
+			}
+			html, err := htmltemplate.New("").
+				Funcs(htmltemplate.FuncMap{"date": date}).
+				ParseFS(templatesFS, "templates/layout.html.tmpl", footer, base+".html.tmpl")
+			if err != nil {
+				return nil, err
+			}
+			t.text[templateKey(locale, name)] = txt
+			t.html[templateKey(locale, name)] = html
+		}
+	}
+	return t, nil
+}
+
+// неизвестный язык заменяется на models.DefaultLocale
+func (t *Templates) Render(locale, name string, data TemplateData) (Message, error) {
+	if !slices.Contains(models.Locales, locale) {
+		locale = models.DefaultLocale
+	}
+	txt, ok := t.text[templateKey(locale, name)]
+	if !ok {
+		return Message{}, errors.New("unknown mail template " + name)
+	}
+	html := t.html[templateKey(locale, name)]
+	data.Brand = t.brand
+
+	var subject, text, body bytes.Buffer
+	if err := txt.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := txt.ExecuteTemplate(&text, "layout", data); err != nil {
+		return Message{}, err
+	}
+	if err := html.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    body.String(),
+	}, nil
+}
@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+// эталонные письма лежат в testdata/<locale>/<name>.{txt,html}, после правки
+// шаблонов они перезаписываются командой go test ./mail -update
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var testBrand = config.Branding{
+	ShopName:     "Online Shop",
+	ShopURL:      "https://shop.example.com",
+	LogoURL:      "https://shop.example.com/logo.png",
+	SupportEmail: "support@example.com",
+	AccentColor:  "#ff6600",
+}
+
+var testData = TemplateData{
+	Link:     "https://shop.example.com/link/<id>?token=a&b",
+	NewEmail: "new@example.com",
+	At:       time.Date(2025, 11, 12, 9, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+}
+
+// у TemplateSecurityAlert текст зависит от события, поэтому каждое событие - отдельный файл
+var alertEvents = []string{AlertPasswordChanged, AlertEmailChangeRequested, AlertAccountLocked}
+
+func TestTemplatesGolden(t *testing.T) {
+	tmpl, err := NewTemplates(testBrand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, locale := range models.Locales {
+		for _, name := range TemplateNames {
+			events := []string{""}
+			if name == TemplateSecurityAlert {
+				events = alertEvents
+			}
+			for _, event := range events {
+				file := name
+				if event != "" {
+					file += "." + event
+				}
+				t.Run(locale+"/"+file, func(t *testing.T) {
+					data := testData
+					data.Event = event
+					msg, err := tmpl.Render(locale, name, data)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if msg.Subject == "" {
+						t.Error("empty subject")
+					}
+					base := filepath.Join("testdata", locale, file)
+					golden(t, base+".txt", "Subject: "+msg.Subject+"\n\n"+msg.Text)
+					golden(t, base+".html", msg.HTML)
+				})
+			}
+		}
+	}
+}
+
+func TestRenderUnknownLocale(t *testing.T) {
+	tmpl, err := NewTemplates(testBrand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmpl.Render("xx", TemplateConfirmation, testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := tmpl.Render(models.DefaultLocale, TemplateConfirmation, testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Error("unknown locale is not replaced with the default one")
+	}
+	if _, err = tmpl.Render(models.DefaultLocale, "unknown", testData); err == nil {
+		t.Error("unknown template rendered without error")
+	}
+}
+
+func golden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./mail -update to create it)", err)
+	}
+	if !bytes.Equal(want, []byte(got)) {
+		t.Errorf("%s differs from the rendered message:\n%s", path, got)
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+// состояние пула соединений на момент запроса /metrics
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	total, idle, acquired, max    *prometheus.Desc
+	acquires, emptyAcquires       *prometheus.Desc
+	canceledAcquires, acquireWait *prometheus.Desc
+}
+
+func NewPoolCollector(stat func() *pgxpool.Stat) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		stat:             stat,
+		total:            desc("conns", "Open connections in the pool."),
+		idle:             desc("idle_conns", "Idle connections in the pool."),
+		acquired:         desc("acquired_conns", "Connections currently in use."),
+		max:              desc("max_conns", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Successful connection acquires."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires canceled by context."),
+		acquireWait:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
+
+// очередь писем, запрашивается из БД при каждом запросе /metrics
+type OutboxCollector struct {
+	stats   func(ctx context.Context) (models.OutboxStats, error)
+	timeout time.Duration
+
+	pending, retrying, dead, oldest *prometheus.Desc
+}
+
+func NewOutboxCollector(stats func(ctx context.Context) (models.OutboxStats, error)) *OutboxCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "mail_outbox", name), help, nil, nil)
+	}
+	return &OutboxCollector{
+		stats:    stats,
+		timeout:  5 * time.Second,
+		pending:  desc("pending", "Emails waiting to be sent, including retries."),
+		retrying: desc("retrying", "Pending emails with at least one failed attempt."),
+		dead:     desc("dead", "Emails that exhausted their attempts."),
+		oldest:   desc("oldest_pending_seconds", "Age of the oldest pending email."),
+	}
+}
+
+func (c *OutboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// при недоступной БД метрики очереди пропадают, а не обнуляются,
+// чтобы не выглядело, будто очередь пуста
+func (c *OutboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	s, err := c.stats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.pending, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(s.Pending))
+	ch <- prometheus.MustNewConstMetric(c.retrying, prometheus.GaugeValue, float64(s.Retrying))
+	ch <- prometheus.MustNewConstMetric(c.dead, prometheus.GaugeValue, float64(s.Dead))
+	ch <- prometheus.MustNewConstMetric(c.oldest, prometheus.GaugeValue, s.OldestPending.Seconds())
+}
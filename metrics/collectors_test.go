@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+func TestPoolCollector(t *testing.T) {
+	// пул не подключается до первого Acquire, поэтому БД не нужна
+	pool, err := pgxpool.New(context.Background(), "postgres://user@127.0.0.1:1/shop?pool_max_conns=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	// отмененный ctx не дожидается соединения
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = pool.Acquire(ctx); err == nil {
+		t.Fatal("acquire with canceled context succeeded")
+	}
+
+	want := `
+# HELP user_service_db_pool_acquired_conns Connections currently in use.
+# TYPE user_service_db_pool_acquired_conns gauge
+user_service_db_pool_acquired_conns 0
+# HELP user_service_db_pool_acquires_total Successful connection acquires.
+# TYPE user_service_db_pool_acquires_total counter
+user_service_db_pool_acquires_total 0
+# HELP user_service_db_pool_canceled_acquires_total Acquires canceled by context.
+# TYPE user_service_db_pool_canceled_acquires_total counter
+user_service_db_pool_canceled_acquires_total 1
+# HELP user_service_db_pool_conns Open connections in the pool.
+# TYPE user_service_db_pool_conns gauge
+user_service_db_pool_conns 0
+# HELP user_service_db_pool_empty_acquires_total Acquires that had to wait for a connection.
+# TYPE user_service_db_pool_empty_acquires_total counter
+user_service_db_pool_empty_acquires_total 0
+# HELP user_service_db_pool_idle_conns Idle connections in the pool.
+# TYPE user_service_db_pool_idle_conns gauge
+user_service_db_pool_idle_conns 0
+# HELP user_service_db_pool_max_conns Maximum size of the pool.
+# TYPE user_service_db_pool_max_conns gauge
+user_service_db_pool_max_conns 3
+`
+	// время ожидания не детерминировано, проверяется только наличие
+	names := []string{
+		"user_service_db_pool_conns",
+		"user_service_db_pool_idle_conns",
+		"user_service_db_pool_acquired_conns",
+		"user_service_db_pool_max_conns",
+		"user_service_db_pool_acquires_total",
+		"user_service_db_pool_empty_acquires_total",
+		"user_service_db_pool_canceled_acquires_total",
+	}
+	c := NewPoolCollector(pool.Stat)
+	if err = testutil.CollectAndCompare(c, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c, "user_service_db_pool_acquire_duration_seconds_total"); n != 1 {
+		t.Errorf("acquire duration: %d series, want 1", n)
+	}
+	problems, err := testutil.CollectAndLint(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		t.Errorf("lint %s: %s", p.Metric, p.Text)
+	}
+}
+
+func TestOutboxCollector(t *testing.T) {
+	t.Run("stats", func(t *testing.T) {
+		c := NewOutboxCollector(func(context.Context) (models.OutboxStats, error) {
+			return models.OutboxStats{Pending: 5, Retrying: 2, Dead: 1, OldestPending: 90 * time.Second}, nil
+		})
+		want := `
+# HELP user_service_mail_outbox_dead Emails that exhausted their attempts.
+# TYPE user_service_mail_outbox_dead gauge
+user_service_mail_outbox_dead 1
+# HELP user_service_mail_outbox_oldest_pending_seconds Age of the oldest pending email.
+# TYPE user_service_mail_outbox_oldest_pending_seconds gauge
+user_service_mail_outbox_oldest_pending_seconds 90
+# HELP user_service_mail_outbox_pending Emails waiting to be sent, including retries.
+# TYPE user_service_mail_outbox_pending gauge
+user_service_mail_outbox_pending 5
+# HELP user_service_mail_outbox_retrying Pending emails with at least one failed attempt.
+# TYPE user_service_mail_outbox_retrying gauge
+user_service_mail_outbox_retrying 2
+`
+		if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("stats error", func(t *testing.T) {
+		c := NewOutboxCollector(func(ctx context.Context) (models.OutboxStats, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("stats are requested without a deadline")
+			}
+			return models.OutboxStats{}, errors.New("db is down")
+		})
+		reg := prometheus.NewPedanticRegistry()
+		reg.MustRegister(c)
+		// ошибка сбора, а не нулевая очередь
+		mfs, err := reg.Gather()
+		if err == nil || !strings.Contains(err.Error(), "db is down") {
+			t.Errorf("gather error: %v, want db is down", err)
+		}
+		if len(mfs) != 0 {
+			t.Errorf("%d metric families gathered, want none", len(mfs))
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "user_service"
+
+// результаты входа, по всплеску LoginInvalidCredentials видно подбор паролей
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginThrottled          = "throttled"
+	LoginLocked             = "locked"
+	LoginError              = "error"
+)
+
+// за что выдана пара токенов
+const (
+	TokensRegister       = "register"
+	TokensLogin          = "login"
+	TokensRefresh        = "refresh"
+	TokensPasswordChange = "password_change"
+)
+
+// результат попытки отправить письмо из очереди
+const (
+	MailSent   = "sent"
+	MailFailed = "failed" // будет повторена
+	MailDead   = "dead"   // попытки исчерпаны
+)
+
+// по чему сработал лимит запросов
+const (
+	LimitIP   = "ip"
+	LimitUser = "user"
+)
+
+// Metrics - метрики сервиса в собственном реестре, чтобы /metrics отдавал только их,
+// метрики процесса и Go и то, что добавлено через Register
+type Metrics struct {
+	reg *prometheus.Registry
+
+	rpcRequests   *prometheus.CounterVec
+	rpcDuration   *prometheus.HistogramVec
+	registrations prometheus.Counter
+	logins        *prometheus.CounterVec
+	tokens        *prometheus.CounterVec
+	rateLimited   *prometheus.CounterVec
+	mails         *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		rpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Handled gRPC requests by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC request latency by method.",
+			// bcrypt при входе и регистрации занимает десятки миллисекунд
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"method"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Successful registrations.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_pairs_issued_total",
+			Help:      "Issued access and refresh token pairs by reason.",
+		}, []string{"reason"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Requests rejected by the rate limiter.",
+		}, []string{"limit"}),
+		mails: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mail_deliveries_total",
+			Help:      "Outbox email delivery attempts by result.",
+		}, []string{"result"}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.rpcRequests,
+		m.rpcDuration,
+		m.registrations,
+		m.logins,
+		m.tokens,
+		m.rateLimited,
+		m.mails,
+	)
+	// нули вместо отсутствующих рядов, иначе rate() по результату входа
+	// ничего не покажет до первой неудачи
+	for _, r := range []string{LoginSuccess, LoginInvalidCredentials, LoginThrottled, LoginLocked, LoginError} {
+		m.logins.WithLabelValues(r)
+	}
+	for _, r := range []string{MailSent, MailFailed, MailDead} {
+		m.mails.WithLabelValues(r)
+	}
+	return m
+}
+
+// для метрик, которые собираются при запросе /metrics, см. PoolCollector
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return m.reg.Register(c)
+}
+
+// ошибка одного сборщика не мешает отдать остальные метрики
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{
+		Registry:      m.reg,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+func (m *Metrics) ObserveRPC(method, code string, d time.Duration) {
+	m.rpcRequests.WithLabelValues(method, code).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+func (m *Metrics) Registered() {
+	m.registrations.Inc()
+}
+
+func (m *Metrics) LoginAttempt(result string) {
+	m.logins.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) TokensIssued(reason string) {
+	m.tokens.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) RateLimited(limit string) {
+	m.rateLimited.WithLabelValues(limit).Inc()
+}
+
+func (m *Metrics) MailDelivery(result string) {
+	m.mails.WithLabelValues(result).Inc()
+}
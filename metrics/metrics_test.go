@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+func TestCounters(t *testing.T) {
+	m := New()
+	m.ObserveRPC("/User/Login", "OK", 30*time.Millisecond)
+	m.ObserveRPC("/User/Login", "OK", 70*time.Millisecond)
+	m.ObserveRPC("/User/Login", "Unauthenticated", time.Millisecond)
+	m.Registered()
+	m.LoginAttempt(LoginSuccess)
+	m.LoginAttempt(LoginInvalidCredentials)
+	m.LoginAttempt(LoginInvalidCredentials)
+	m.TokensIssued(TokensLogin)
+	m.TokensIssued(TokensRefresh)
+	m.RateLimited(LimitIP)
+	m.MailDelivery(MailSent)
+
+	// результаты входа и писем есть и до первого события
+	want := `
+# HELP user_service_grpc_requests_total Handled gRPC requests by method and status code.
+# TYPE user_service_grpc_requests_total counter
+user_service_grpc_requests_total{code="OK",method="/User/Login"} 2
+user_service_grpc_requests_total{code="Unauthenticated",method="/User/Login"} 1
+# HELP user_service_logins_total Login attempts by result.
+# TYPE user_service_logins_total counter
+user_service_logins_total{result="error"} 0
+user_service_logins_total{result="invalid_credentials"} 2
+user_service_logins_total{result="locked"} 0
+user_service_logins_total{result="success"} 1
+user_service_logins_total{result="throttled"} 0
+# HELP user_service_mail_deliveries_total Outbox email delivery attempts by result.
+# TYPE user_service_mail_deliveries_total counter
+user_service_mail_deliveries_total{result="dead"} 0
+user_service_mail_deliveries_total{result="failed"} 0
+user_service_mail_deliveries_total{result="sent"} 1
+# HELP user_service_rate_limited_requests_total Requests rejected by the rate limiter.
+# TYPE user_service_rate_limited_requests_total counter
+user_service_rate_limited_requests_total{limit="ip"} 1
+# HELP user_service_registrations_total Successful registrations.
+# TYPE user_service_registrations_total counter
+user_service_registrations_total 1
+# HELP user_service_token_pairs_issued_total Issued access and refresh token pairs by reason.
+# TYPE user_service_token_pairs_issued_total counter
+user_service_token_pairs_issued_total{reason="login"} 1
+user_service_token_pairs_issued_total{reason="refresh"} 1
+`
+	err := testutil.GatherAndCompare(m.reg, strings.NewReader(want),
+		"user_service_grpc_requests_total",
+		"user_service_logins_total",
+		"user_service_mail_deliveries_total",
+		"user_service_rate_limited_requests_total",
+		"user_service_registrations_total",
+		"user_service_token_pairs_issued_total",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// одна гистограмма на метод, независимо от кода ответа
+	if n := testutil.CollectAndCount(m.rpcDuration); n != 1 {
+		t.Errorf("rpc duration: %d series, want 1", n)
+	}
+	if got := testutil.ToFloat64(m.registrations); got != 1 {
+		t.Errorf("registrations: %v, want 1", got)
+	}
+}
+
+func TestRegisterAndHandler(t *testing.T) {
+	m := New()
+	c := NewOutboxCollector(func(context.Context) (models.OutboxStats, error) {
+		return models.OutboxStats{}, errors.New("db is down")
+	})
+	if err := m.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register(c); err == nil {
+		t.Error("collector registered twice")
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	// упавший сборщик не мешает отдать остальные метрики
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	for _, name := range []string{"user_service_logins_total", "go_goroutines"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("%s is not exported", name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// для событий, которые ничего не меняют в БД, например входа в аккаунт
+func (r *Repository) AddAuditEntry(ctx context.Context, action, targetID string, before, after any) error {
+	return writeAudit(ctx, r.q, action, targetID, before, after)
+}
+
+func (r *Repository) GetAuditLog(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	rows, err := r.q.GetAuditLog(ctx, db.GetAuditLogParams{
+		TargetID: nullableText(f.TargetID),
+		ActorID:  nullableText(f.ActorID),
+		Action:   nullableText(f.Action),
+		FromTime: pgtype.Timestamptz{Time: f.From, Valid: !f.From.IsZero()},
+		ToTime:   pgtype.Timestamptz{Time: f.To, Valid: !f.To.IsZero()},
+		BeforeID: pgtype.Int8{Int64: f.BeforeID, Valid: f.BeforeID > 0},
+		MaxRows:  int32(f.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.AuditEntry, 0, len(rows))
+	for _, e := range rows {
+		res = append(res, models.AuditEntry{
+			ID:        e.ID,
+			ActorID:   e.ActorID.String,
+			TargetID:  e.TargetID.String,
+			Action:    e.Action,
+			IP:        e.Ip.String,
+			Before:    e.Before,
+			After:     e.After,
+			CreatedAt: e.CreatedAt.Time,
+		})
+	}
+	return res, nil
+}
+
+// кто и откуда выполнил действие, берется из данных запроса в контексте,
+// поэтому при вызове из фоновой задачи actor_id и ip остаются пустыми
+func writeAudit(ctx context.Context, q *db.Queries, action, targetID string, before, after any) error {
+	var ld logger.LogData
+	if l, ok := ctx.Value(logger.LogDataKey).(logger.LogData); ok {
+		ld = l
+	}
+	b, err := marshalAudit(before)
+	if err != nil {
+		return err
+	}
+	a, err := marshalAudit(after)
+	if err != nil {
+		return err
+	}
+	return q.AddAuditEntry(ctx, db.AddAuditEntryParams{
+		ActorID:  nullableText(ld.UserID),
+		TargetID: nullableText(targetID),
+		Action:   action,
+		Ip:       nullableText(ld.IPAddress),
+		Before:   b,
+		After:    a,
+	})
+}
+
+func marshalAudit(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
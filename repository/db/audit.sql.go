@@ -0,0 +1,100 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: audit.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addAuditEntry = `-- name: AddAuditEntry :exec
+INSERT INTO audit_log(actor_id, target_id, action, ip, before, after)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type AddAuditEntryParams struct {
+	ActorID  pgtype.Text
+	TargetID pgtype.Text
+	Action   string
+	Ip       pgtype.Text
+	Before   []byte
+	After    []byte
+}
+
+func (q *Queries) AddAuditEntry(ctx context.Context, arg AddAuditEntryParams) error {
+	_, err := q.db.Exec(ctx, addAuditEntry,
+		arg.ActorID,
+		arg.TargetID,
+		arg.Action,
+		arg.Ip,
+		arg.Before,
+		arg.After,
+	)
+	return err
+}
+
+const getAuditLog = `-- name: GetAuditLog :many
+SELECT id, actor_id, target_id, action, ip, before, after, created_at
+FROM audit_log
+WHERE ($1::varchar IS NULL OR target_id = $1)
+    AND ($2::varchar IS NULL OR actor_id = $2)
+    AND ($3::varchar IS NULL OR action = $3)
+    AND ($4::timestamptz IS NULL OR created_at >= $4)
+    AND ($5::timestamptz IS NULL OR created_at < $5)
+    AND ($6::bigint IS NULL OR id < $6)
+ORDER BY id DESC
+LIMIT $7
+`
+
+type GetAuditLogParams struct {
+	TargetID pgtype.Text
+	ActorID  pgtype.Text
+	Action   pgtype.Text
+	FromTime pgtype.Timestamptz
+	ToTime   pgtype.Timestamptz
+	BeforeID pgtype.Int8
+	MaxRows  int32
+}
+
+// пустые фильтры не применяются, страницы идут от новых записей к старым,
+// before_id - id последней записи предыдущей страницы
+func (q *Queries) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, getAuditLog,
+		arg.TargetID,
+		arg.ActorID,
+		arg.Action,
+		arg.FromTime,
+		arg.ToTime,
+		arg.BeforeID,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorID,
+			&i.TargetID,
+			&i.Action,
+			&i.Ip,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
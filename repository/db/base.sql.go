@@ -7,42 +7,47 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const changeEmail = `-- name: ChangeEmail :execrows
+const anonymizeUser = `-- name: AnonymizeUser :execrows
 UPDATE users
-SET email = $1
-WHERE id = $2
+SET name = 'deleted user',
+    email = 'deleted-' || id || '@deleted.invalid',
+    password = '',
+    email_confirmed = FALSE,
+    pending_email = NULL,
+    birthday = NULL,
+    phone = NULL,
+    shipping_country = NULL,
+    shipping_city = NULL,
+    shipping_street = NULL,
+    shipping_postal_code = NULL,
+    deletion_scheduled_at = NULL,
+    deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-type ChangeEmailParams struct {
-	Email string
-	ID    string
-}
-
-// асинхронно и не обновлять, пока новая почта не будет подтверждена
-func (q *Queries) ChangeEmail(ctx context.Context, arg ChangeEmailParams) (int64, error) {
-	result, err := q.db.Exec(ctx, changeEmail, arg.Email, arg.ID)
+// вместо удаления строки затираются персональные данные, id остается.
+// пустой пароль не совпадет ни с одним хэшем бкрипта, поэтому войти нельзя,
+// а почта уникальна за счет id
+func (q *Queries) AnonymizeUser(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, anonymizeUser, id)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
-const changeName = `-- name: ChangeName :execrows
+const cancelDeletion = `-- name: CancelDeletion :execrows
 UPDATE users
-SET name = $1
-WHERE id = $2
+SET deletion_scheduled_at = NULL
+WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL
 `
 
-type ChangeNameParams struct {
-	Name string
-	ID   string
-}
-
-// впоследствии этот метод надо расширить на день рождения и адрес
-func (q *Queries) ChangeName(ctx context.Context, arg ChangeNameParams) (int64, error) {
-	result, err := q.db.Exec(ctx, changeName, arg.Name, arg.ID)
+func (q *Queries) CancelDeletion(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelDeletion, id)
 	if err != nil {
 		return 0, err
 	}
@@ -52,7 +57,7 @@ func (q *Queries) ChangeName(ctx context.Context, arg ChangeNameParams) (int64,
 const changePassword = `-- name: ChangePassword :execrows
 UPDATE users
 SET password=$1
-WHERE id = $2
+WHERE id = $2 AND deleted_at IS NULL
 `
 
 type ChangePasswordParams struct {
@@ -83,16 +88,60 @@ func (q *Queries) ConfirmEmail(ctx context.Context, id string) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
-const createUser = `-- name: CreateUser :exec
-INSERT INTO users(id, name, email, password)
+const confirmEmailChange = `-- name: ConfirmEmailChange :execrows
+UPDATE users
+SET email = pending_email, pending_email = NULL, email_confirmed = TRUE
+WHERE id = $1 AND pending_email = $2 AND deleted_at IS NULL
+`
+
+type ConfirmEmailChangeParams struct {
+	ID           string
+	PendingEmail pgtype.Text
+}
+
+// сравнение с pending_email не дает подтвердить адрес, который успели заменить
+// после отправки письма; новая почта сразу считается подтвержденной - ссылка пришла на неё
+func (q *Queries) ConfirmEmailChange(ctx context.Context, arg ConfirmEmailChangeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, confirmEmailChange, arg.ID, arg.PendingEmail)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens(token_hash, user_id, family_id, expires_at)
 VALUES ($1, $2, $3, $4)
 `
 
+type CreateRefreshTokenParams struct {
+	TokenHash string
+	UserID    string
+	FamilyID  string
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
+	_, err := q.db.Exec(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createUser = `-- name: CreateUser :exec
+INSERT INTO users(id, name, email, password, locale)
+VALUES ($1, $2, $3, $4, $5)
+`
+
 type CreateUserParams struct {
 	ID       string
 	Name     string
 	Email    string
 	Password string
+	Locale   string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
@@ -101,99 +150,199 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
 		arg.Name,
 		arg.Email,
 		arg.Password,
+		arg.Locale,
 	)
 	return err
 }
 
-const deleteAdmin = `-- name: DeleteAdmin :execrows
-DELETE FROM admins
-WHERE id = $1
+const deletePasswordHistory = `-- name: DeletePasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id = $1
 `
 
-// нужно проверять, чтобы было isCore
-func (q *Queries) DeleteAdmin(ctx context.Context, id string) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteAdmin, id)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected(), nil
+func (q *Queries) DeletePasswordHistory(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deletePasswordHistory, userID)
+	return err
 }
 
-const deleteModer = `-- name: DeleteModer :execrows
-DELETE FROM moders
-WHERE id = $1
+const getDueDeletions = `-- name: GetDueDeletions :many
+SELECT id
+FROM users
+WHERE deletion_scheduled_at <= now() AND deleted_at IS NULL
+ORDER BY deletion_scheduled_at
+LIMIT $1
 `
 
-// нужно проверять, чтобы права на модерацию не убрали у админа
-func (q *Queries) DeleteModer(ctx context.Context, id string) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteModer, id)
+func (q *Queries) GetDueDeletions(ctx context.Context, limit int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, getDueDeletions, limit)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return result.RowsAffected(), nil
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const deleteUser = `-- name: DeleteUser :execrows
-DELETE FROM users
-WHERE id = $1
+const getEmailForUpdate = `-- name: GetEmailForUpdate :one
+SELECT email
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
 `
 
-// нужно проверять, чтобы было право администратора
-func (q *Queries) DeleteUser(ctx context.Context, id string) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteUser, id)
+// старая почта нужна для журнала аудита (в маскированном виде)
+func (q *Queries) GetEmailForUpdate(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, getEmailForUpdate, id)
+	var email string
+	err := row.Scan(&email)
+	return email, err
+}
+
+const getPasswordHistory = `-- name: GetPasswordHistory :many
+SELECT password
+FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetPasswordHistoryParams struct {
+	UserID string
+	Limit  int32
+}
+
+// текущий пароль в выборку не входит, он есть в users
+func (q *Queries) GetPasswordHistory(ctx context.Context, arg GetPasswordHistoryParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getPasswordHistory, arg.UserID, arg.Limit)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return result.RowsAffected(), nil
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var password string
+		if err := rows.Scan(&password); err != nil {
+			return nil, err
+		}
+		items = append(items, password)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getAdmin = `-- name: GetAdmin :one
-SELECT id, is_core 
-FROM admins
-WHERE id = $1
+const getPermissions = `-- name: GetPermissions :many
+SELECT users.id, users.email_confirmed,
+    (users.banned_at IS NOT NULL)::boolean AS is_banned,
+    ARRAY(
+        SELECT user_roles.role_id
+        FROM user_roles
+        WHERE user_roles.user_id = users.id
+        ORDER BY user_roles.role_id
+    )::varchar[] AS roles,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
+FROM users
+WHERE users.id = ANY($1::varchar[]) AND users.deleted_at IS NULL
 `
 
-func (q *Queries) GetAdmin(ctx context.Context, id string) (Admin, error) {
-	row := q.db.QueryRow(ctx, getAdmin, id)
-	var i Admin
-	err := row.Scan(&i.ID, &i.IsCore)
-	return i, err
+type GetPermissionsRow struct {
+	ID             string
+	EmailConfirmed bool
+	IsBanned       bool
+	Roles          []string
+	Permissions    []string
 }
 
-const getModer = `-- name: GetModer :one
-SELECT id 
-FROM moders
-WHERE id = $1
+// права для других сервисов, удаленные пользователи считаются несуществующими
+func (q *Queries) GetPermissions(ctx context.Context, ids []string) ([]GetPermissionsRow, error) {
+	rows, err := q.db.Query(ctx, getPermissions, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPermissionsRow
+	for rows.Next() {
+		var i GetPermissionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EmailConfirmed,
+			&i.IsBanned,
+			&i.Roles,
+			&i.Permissions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT token_hash, user_id, family_id, expires_at, created_at, used_at, revoked_at
+FROM refresh_tokens
+WHERE token_hash = $1
 `
 
-func (q *Queries) GetModer(ctx context.Context, id string) (string, error) {
-	row := q.db.QueryRow(ctx, getModer, id)
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, getRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.FamilyID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UsedAt,
+		&i.RevokedAt,
+	)
+	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT users.id, users.name, users.password,
-    CASE WHEN moders.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_moder, 
-    CASE WHEN admins.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_admin,
-    CASE WHEN admins.iscore IS NOT NULL THEN admins.iscore ELSE FALSE END AS is_core
+SELECT users.id, users.name, users.password, users.locale,
+    (users.deletion_scheduled_at IS NOT NULL)::boolean AS deletion_scheduled,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
 FROM users
-    LEFT JOIN moders ON users.id = moders.id
-    LEFT JOIN admins ON users.id = admins.id
-WHERE users.email = $1
+WHERE users.email = $1 AND users.deleted_at IS NULL
 `
 
 type GetUserByEmailRow struct {
-	ID       string
-	Name     string
-	Password string
-	IsModer  bool
-	IsAdmin  bool
-	IsCore   bool
+	ID                string
+	Name              string
+	Password          string
+	Locale            string
+	DeletionScheduled bool
+	Permissions       []string
 }
 
 // используется при логине (инфа добавляется в токен), поэтому
-// нужна дополнительная информация о правах (модер, админ, isCore),
+// нужен список прав пользователя,
 // чтобы при каждом GET запросе не идти в БД
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
 	row := q.db.QueryRow(ctx, getUserByEmail, email)
@@ -202,19 +351,22 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 		&i.ID,
 		&i.Name,
 		&i.Password,
-		&i.IsModer,
-		&i.IsAdmin,
-		&i.IsCore,
+		&i.Locale,
+		&i.DeletionScheduled,
+		&i.Permissions,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name, email, password, email_confirmed 
+SELECT id, name, email, password, email_confirmed, pending_email, birthday, phone, shipping_country, shipping_city, shipping_street, shipping_postal_code, deletion_scheduled_at, deleted_at, banned_at, locale 
 FROM users
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 `
 
+// удаленные (анонимизированные) пользователи в запросах ниже считаются
+// несуществующими, иначе по старой ссылке из письма можно было бы вернуть
+// анонимизированной записи пароль и войти в нее
 func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
 	row := q.db.QueryRow(ctx, getUserByID, id)
 	var i User
@@ -224,19 +376,79 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.EmailConfirmed,
+		&i.PendingEmail,
+		&i.Birthday,
+		&i.Phone,
+		&i.ShippingCountry,
+		&i.ShippingCity,
+		&i.ShippingStreet,
+		&i.ShippingPostalCode,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.BannedAt,
+		&i.Locale,
 	)
 	return i, err
 }
 
+const getUserEmailsForUpdate = `-- name: GetUserEmailsForUpdate :one
+SELECT email, pending_email
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type GetUserEmailsForUpdateRow struct {
+	Email        string
+	PendingEmail pgtype.Text
+}
+
+// почты удаляемого пользователя, чтобы затереть их в очереди писем и счетчиках входа
+func (q *Queries) GetUserEmailsForUpdate(ctx context.Context, id string) (GetUserEmailsForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getUserEmailsForUpdate, id)
+	var i GetUserEmailsForUpdateRow
+	err := row.Scan(&i.Email, &i.PendingEmail)
+	return i, err
+}
+
+const getUserTokenByID = `-- name: GetUserTokenByID :one
+SELECT users.id, users.name,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
+FROM users
+WHERE users.id = $1 AND users.deleted_at IS NULL
+`
+
+type GetUserTokenByIDRow struct {
+	ID          string
+	Name        string
+	Permissions []string
+}
+
+// то же, что GetUserByEmail, но для перевыпуска токенов по рефреш токену,
+// чтобы в новые токены попадали актуальные имя и права
+func (q *Queries) GetUserTokenByID(ctx context.Context, id string) (GetUserTokenByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserTokenByID, id)
+	var i GetUserTokenByIDRow
+	err := row.Scan(&i.ID, &i.Name, &i.Permissions)
+	return i, err
+}
+
 const getUsersByEmail = `-- name: GetUsersByEmail :many
 SELECT users.id, users.name, users.email, users.email_confirmed,
-	CASE WHEN moders.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_moder, 
-	CASE WHEN admins.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_admin,
-	CASE WHEN admins.iscore IS NOT NULL THEN admins.iscore ELSE FALSE END AS is_core
+    ARRAY(
+        SELECT user_roles.role_id
+        FROM user_roles
+        WHERE user_roles.user_id = users.id
+        ORDER BY user_roles.role_id
+    )::varchar[] AS roles
 FROM users 
-	LEFT JOIN moders ON users.id = moders.id
-	LEFT JOIN admins ON users.id = admins.id
-WHERE users.email LIKE $1
+WHERE users.email LIKE $1 AND users.deleted_at IS NULL
 `
 
 type GetUsersByEmailRow struct {
@@ -244,14 +456,12 @@ type GetUsersByEmailRow struct {
 	Name           string
 	Email          string
 	EmailConfirmed bool
-	IsModer        bool
-	IsAdmin        bool
-	IsCore         bool
+	Roles          []string
 }
 
 // этот метод вызывается только администратором,
-// поэтому нужна полная инфоормация о правах (модератор, админ, isCore),
-// чтобы отобразить её в интерфейсе управления пользователями
+// поэтому нужны роли пользователя,
+// чтобы отобразить их в интерфейсе управления пользователями
 func (q *Queries) GetUsersByEmail(ctx context.Context, email string) ([]GetUsersByEmailRow, error) {
 	rows, err := q.db.Query(ctx, getUsersByEmail, email)
 	if err != nil {
@@ -266,9 +476,7 @@ func (q *Queries) GetUsersByEmail(ctx context.Context, email string) ([]GetUsers
 			&i.Name,
 			&i.Email,
 			&i.EmailConfirmed,
-			&i.IsModer,
-			&i.IsAdmin,
-			&i.IsCore,
+			&i.Roles,
 		); err != nil {
 			return nil, err
 		}
@@ -280,37 +488,179 @@ func (q *Queries) GetUsersByEmail(ctx context.Context, email string) ([]GetUsers
 	return items, nil
 }
 
-const promoteAdmin = `-- name: PromoteAdmin :exec
-INSERT INTO admins(id, isCore)
-VALUES($1, FALSE)
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL
 `
 
-// при назначении админом не забыть в транзакции дать права модератора
-func (q *Queries) PromoteAdmin(ctx context.Context, id string) error {
-	_, err := q.db.Exec(ctx, promoteAdmin, id)
-	return err
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const promoteCoreAdmin = `-- name: PromoteCoreAdmin :execrows
-UPDATE admins
-SET isCore = TRUE
-WHERE id = $1
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL
 `
 
-func (q *Queries) PromoteCoreAdmin(ctx context.Context, id string) (int64, error) {
-	result, err := q.db.Exec(ctx, promoteCoreAdmin, id)
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserRefreshTokens, userID)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
-const promoteModer = `-- name: PromoteModer :exec
-INSERT INTO moders(id)
-VALUES($1)
+const savePasswordToHistory = `-- name: SavePasswordToHistory :exec
+INSERT INTO password_history(user_id, password)
+SELECT users.id, users.password
+FROM users
+WHERE users.id = $1
 `
 
-func (q *Queries) PromoteModer(ctx context.Context, id string) error {
-	_, err := q.db.Exec(ctx, promoteModer, id)
+// вызывается в одной транзакции с ChangePassword, до него
+func (q *Queries) SavePasswordToHistory(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, savePasswordToHistory, id)
 	return err
 }
+
+const scheduleDeletion = `-- name: ScheduleDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = $1
+WHERE id = $2 AND deleted_at IS NULL
+`
+
+type ScheduleDeletionParams struct {
+	DeletionScheduledAt pgtype.Timestamptz
+	ID                  string
+}
+
+func (q *Queries) ScheduleDeletion(ctx context.Context, arg ScheduleDeletionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, scheduleDeletion, arg.DeletionScheduledAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setBanned = `-- name: SetBanned :one
+UPDATE users
+SET banned_at = CASE WHEN $1::boolean THEN COALESCE(users.banned_at, now()) END
+FROM (
+    SELECT u.id, u.banned_at
+    FROM users AS u
+    WHERE u.id = $2 AND u.deleted_at IS NULL
+    FOR UPDATE
+) AS prev
+WHERE users.id = prev.id
+RETURNING (prev.banned_at IS NOT NULL)::boolean AS was_banned
+`
+
+type SetBannedParams struct {
+	Banned bool
+	ID     string
+}
+
+// was_banned - состояние до изменения, чтобы не писать в журнал повторный бан.
+// время первого бана сохраняется
+func (q *Queries) SetBanned(ctx context.Context, arg SetBannedParams) (bool, error) {
+	row := q.db.QueryRow(ctx, setBanned, arg.Banned, arg.ID)
+	var was_banned bool
+	err := row.Scan(&was_banned)
+	return was_banned, err
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :execrows
+UPDATE users
+SET pending_email = $1
+WHERE id = $2 AND deleted_at IS NULL
+`
+
+type SetPendingEmailParams struct {
+	PendingEmail pgtype.Text
+	ID           string
+}
+
+// почта не обновляется, пока новая не будет подтверждена, до этого она лежит в pending_email
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setPendingEmail, arg.PendingEmail, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateProfile = `-- name: UpdateProfile :execrows
+UPDATE users
+SET name = CASE WHEN $1::boolean THEN $2::varchar ELSE name END,
+    birthday = CASE WHEN $3::boolean THEN $4::date ELSE birthday END,
+    phone = CASE WHEN $5::boolean THEN $6::varchar ELSE phone END,
+    shipping_country = CASE WHEN $7::boolean THEN $8::varchar ELSE shipping_country END,
+    shipping_city = CASE WHEN $7::boolean THEN $9::varchar ELSE shipping_city END,
+    shipping_street = CASE WHEN $7::boolean THEN $10::varchar ELSE shipping_street END,
+    shipping_postal_code = CASE WHEN $7::boolean THEN $11::varchar ELSE shipping_postal_code END,
+    locale = CASE WHEN $12::boolean THEN $13::varchar ELSE locale END
+WHERE id = $14
+`
+
+type UpdateProfileParams struct {
+	SetName            bool
+	Name               string
+	SetBirthday        bool
+	Birthday           pgtype.Date
+	SetPhone           bool
+	Phone              pgtype.Text
+	SetShippingAddress bool
+	ShippingCountry    pgtype.Text
+	ShippingCity       pgtype.Text
+	ShippingStreet     pgtype.Text
+	ShippingPostalCode pgtype.Text
+	SetLocale          bool
+	Locale             string
+	ID                 string
+}
+
+// частичное обновление профиля: меняются только поля с флагом set_*,
+// NULL в значении очищает поле
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateProfile,
+		arg.SetName,
+		arg.Name,
+		arg.SetBirthday,
+		arg.Birthday,
+		arg.SetPhone,
+		arg.Phone,
+		arg.SetShippingAddress,
+		arg.ShippingCountry,
+		arg.ShippingCity,
+		arg.ShippingStreet,
+		arg.ShippingPostalCode,
+		arg.SetLocale,
+		arg.Locale,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useRefreshToken = `-- name: UseRefreshToken :execrows
+UPDATE refresh_tokens
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL
+`
+
+// условие на used_at и revoked_at защищает от гонки двух одновременных ротаций
+func (q *Queries) UseRefreshToken(ctx context.Context, tokenHash string) (int64, error) {
+	result, err := q.db.Exec(ctx, useRefreshToken, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,147 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: login.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimLoginAttempt = `-- name: ClaimLoginAttempt :one
+INSERT INTO login_failures AS f (email, ip, failures)
+VALUES ($1, $2, 1)
+ON CONFLICT (email, ip) DO UPDATE
+SET failures = CASE WHEN f.last_failure_at < $3::timestamptz THEN 1 ELSE f.failures + 1 END,
+    last_failure_at = now(),
+    locked = $4::int > 0
+        AND (CASE WHEN f.last_failure_at < $3::timestamptz THEN 1 ELSE f.failures + 1 END) >= $4::int,
+    blocked_until = CASE
+        WHEN $4::int > 0
+            AND (CASE WHEN f.last_failure_at < $3::timestamptz THEN 1 ELSE f.failures + 1 END) >= $4::int
+            THEN now() + make_interval(secs => $5::float8)
+        WHEN (CASE WHEN f.last_failure_at < $3::timestamptz THEN 1 ELSE f.failures + 1 END) > $6::int
+            THEN now() + make_interval(secs => LEAST(
+                $7::float8 * power(2, LEAST((CASE WHEN f.last_failure_at < $3::timestamptz THEN 1 ELSE f.failures + 1 END) - $6::int - 1, 20)),
+                $8::float8))
+    END
+WHERE f.blocked_until IS NULL OR f.blocked_until <= now()
+RETURNING failures, locked
+`
+
+type ClaimLoginAttemptParams struct {
+	Email           string
+	Ip              string
+	WindowStart     pgtype.Timestamptz
+	LockThreshold   int32
+	LockSecs        float64
+	FreeAttempts    int32
+	BackoffBaseSecs float64
+	BackoffMaxSecs  float64
+}
+
+type ClaimLoginAttemptRow struct {
+	Failures int32
+	Locked   bool
+}
+
+// попытка засчитывается до проверки пароля, а успешный вход сбрасывает счетчик.
+// проверка паузы, увеличение счетчика и новая пауза - один запрос под блокировкой строки,
+// поэтому параллельные попытки не проходят все разом мимо проверки.
+// пока действует пауза, строка не возвращается.
+// счетчик начинается заново, если с прошлой попытки прошло больше окна.
+// первая попытка всегда бесплатная (free_attempts >= 1), lock_threshold = 0 - без блокировки
+func (q *Queries) ClaimLoginAttempt(ctx context.Context, arg ClaimLoginAttemptParams) (ClaimLoginAttemptRow, error) {
+	row := q.db.QueryRow(ctx, claimLoginAttempt,
+		arg.Email,
+		arg.Ip,
+		arg.WindowStart,
+		arg.LockThreshold,
+		arg.LockSecs,
+		arg.FreeAttempts,
+		arg.BackoffBaseSecs,
+		arg.BackoffMaxSecs,
+	)
+	var i ClaimLoginAttemptRow
+	err := row.Scan(&i.Failures, &i.Locked)
+	return i, err
+}
+
+const deleteStaleLoginFailures = `-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until < now())
+`
+
+func (q *Queries) DeleteStaleLoginFailures(ctx context.Context, lastFailureAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleLoginFailures, lastFailureAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserLoginFailures = `-- name: DeleteUserLoginFailures :exec
+DELETE FROM login_failures
+WHERE email = ANY($1::varchar[])
+`
+
+func (q *Queries) DeleteUserLoginFailures(ctx context.Context, emails []string) error {
+	_, err := q.db.Exec(ctx, deleteUserLoginFailures, emails)
+	return err
+}
+
+const getLoginFailures = `-- name: GetLoginFailures :many
+SELECT email, ip, failures, last_failure_at, blocked_until, locked
+FROM login_failures
+WHERE email = $1 AND ip IN ('', $2)
+`
+
+type GetLoginFailuresParams struct {
+	Email string
+	Ip    string
+}
+
+func (q *Queries) GetLoginFailures(ctx context.Context, arg GetLoginFailuresParams) ([]LoginFailure, error) {
+	rows, err := q.db.Query(ctx, getLoginFailures, arg.Email, arg.Ip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LoginFailure
+	for rows.Next() {
+		var i LoginFailure
+		if err := rows.Scan(
+			&i.Email,
+			&i.Ip,
+			&i.Failures,
+			&i.LastFailureAt,
+			&i.BlockedUntil,
+			&i.Locked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetLoginFailures = `-- name: ResetLoginFailures :exec
+DELETE FROM login_failures
+WHERE email = $1 AND ip IN ('', $2)
+`
+
+type ResetLoginFailuresParams struct {
+	Email string
+	Ip    string
+}
+
+func (q *Queries) ResetLoginFailures(ctx context.Context, arg ResetLoginFailuresParams) error {
+	_, err := q.db.Exec(ctx, resetLoginFailures, arg.Email, arg.Ip)
+	return err
+}
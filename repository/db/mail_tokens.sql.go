@@ -0,0 +1,113 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: mail_tokens.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addMailToken = `-- name: AddMailToken :execrows
+INSERT INTO mail_tokens(key, user_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE
+SET user_id = EXCLUDED.user_id, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+WHERE mail_tokens.expires_at <= now()
+`
+
+type AddMailTokenParams struct {
+	Key       string
+	UserID    string
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+}
+
+// истекший токен с тем же ключом заменяется, действующий остается:
+// 0 строк - письмо с таким токеном уже отправлено
+func (q *Queries) AddMailToken(ctx context.Context, arg AddMailTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addMailToken,
+		arg.Key,
+		arg.UserID,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredMailTokens = `-- name: DeleteExpiredMailTokens :execrows
+DELETE FROM mail_tokens
+WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredMailTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredMailTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteMailToken = `-- name: DeleteMailToken :exec
+DELETE FROM mail_tokens
+WHERE key = $1
+`
+
+func (q *Queries) DeleteMailToken(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteMailToken, key)
+	return err
+}
+
+const deleteUserMailTokens = `-- name: DeleteUserMailTokens :exec
+DELETE FROM mail_tokens
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserMailTokens(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserMailTokens, userID)
+	return err
+}
+
+const mailTokenValid = `-- name: MailTokenValid :one
+SELECT EXISTS (
+    SELECT 1 FROM mail_tokens
+    WHERE key = $1 AND token_hash = $2 AND expires_at > now()
+)
+`
+
+type MailTokenValidParams struct {
+	Key       string
+	TokenHash string
+}
+
+func (q *Queries) MailTokenValid(ctx context.Context, arg MailTokenValidParams) (bool, error) {
+	row := q.db.QueryRow(ctx, mailTokenValid, arg.Key, arg.TokenHash)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const useMailToken = `-- name: UseMailToken :execrows
+DELETE FROM mail_tokens
+WHERE key = $1 AND token_hash = $2 AND expires_at > now()
+`
+
+type UseMailTokenParams struct {
+	Key       string
+	TokenHash string
+}
+
+// проверка и удаление одним запросом, чтобы токен нельзя было использовать дважды
+func (q *Queries) UseMailToken(ctx context.Context, arg UseMailTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useMailToken, arg.Key, arg.TokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
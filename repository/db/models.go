@@ -4,19 +4,115 @@
 
 package db
 
-type Admin struct {
-	ID     string
-	IsCore bool
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AuditLog struct {
+	ID        int64
+	ActorID   pgtype.Text
+	TargetID  pgtype.Text
+	Action    string
+	Ip        pgtype.Text
+	Before    []byte
+	After     []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type LoginFailure struct {
+	Email         string
+	Ip            string
+	Failures      int32
+	LastFailureAt pgtype.Timestamptz
+	BlockedUntil  pgtype.Timestamptz
+	Locked        bool
+}
+
+type MailOutbox struct {
+	ID            int64
+	Template      string
+	Locale        string
+	Recipient     string
+	Data          []byte
+	Attempts      int32
+	NextAttemptAt pgtype.Timestamptz
+	LastError     pgtype.Text
+	MessageID     pgtype.Text
+	CreatedAt     pgtype.Timestamptz
+	SentAt        pgtype.Timestamptz
+	DeadAt        pgtype.Timestamptz
 }
 
-type Moder struct {
-	ID string
+type MailToken struct {
+	Key       string
+	UserID    string
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+}
+
+type PasswordHistory struct {
+	ID        int64
+	UserID    string
+	Password  string
+	CreatedAt pgtype.Timestamptz
+}
+
+type RateLimit struct {
+	Key string
+	Tat pgtype.Timestamptz
+}
+
+type RefreshToken struct {
+	TokenHash string
+	UserID    string
+	FamilyID  string
+	ExpiresAt pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	RevokedAt pgtype.Timestamptz
+}
+
+type Role struct {
+	ID          string
+	Description string
+}
+
+type RoleAssignment struct {
+	ID        int64
+	UserID    string
+	RoleID    string
+	Action    string
+	ActorID   pgtype.Text
+	CreatedAt pgtype.Timestamptz
+}
+
+type RolePermission struct {
+	RoleID     string
+	Permission string
 }
 
 type User struct {
-	ID             string
-	Name           string
-	Email          string
-	Password       string
-	EmailConfirmed bool
+	ID                  string
+	Name                string
+	Email               string
+	Password            string
+	EmailConfirmed      bool
+	PendingEmail        pgtype.Text
+	Birthday            pgtype.Date
+	Phone               pgtype.Text
+	ShippingCountry     pgtype.Text
+	ShippingCity        pgtype.Text
+	ShippingStreet      pgtype.Text
+	ShippingPostalCode  pgtype.Text
+	DeletionScheduledAt pgtype.Timestamptz
+	DeletedAt           pgtype.Timestamptz
+	BannedAt            pgtype.Timestamptz
+	Locale              string
+}
+
+type UserRole struct {
+	UserID    string
+	RoleID    string
+	GrantedBy pgtype.Text
+	GrantedAt pgtype.Timestamptz
 }
@@ -0,0 +1,214 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimMails = `-- name: ClaimMails :many
+UPDATE mail_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = now() + make_interval(secs => $1::float8)
+WHERE id IN (
+    SELECT id
+    FROM mail_outbox
+    WHERE sent_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, template, locale, recipient, data, attempts
+`
+
+type ClaimMailsParams struct {
+	LeaseSecs float64
+	MaxRows   int32
+}
+
+type ClaimMailsRow struct {
+	ID        int64
+	Template  string
+	Locale    string
+	Recipient string
+	Data      []byte
+	Attempts  int32
+}
+
+// письма забираются с арендой: next_attempt_at сдвигается на lease, и если
+// воркер упадет, письмо заберет другой после ее окончания.
+// SKIP LOCKED не дает двум репликам забрать одно письмо
+func (q *Queries) ClaimMails(ctx context.Context, arg ClaimMailsParams) ([]ClaimMailsRow, error) {
+	rows, err := q.db.Query(ctx, claimMails, arg.LeaseSecs, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimMailsRow
+	for rows.Next() {
+		var i ClaimMailsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Template,
+			&i.Locale,
+			&i.Recipient,
+			&i.Data,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteSentMails = `-- name: DeleteSentMails :execrows
+DELETE FROM mail_outbox
+WHERE sent_at < $1
+`
+
+// недоставленные письма остаются для разбора
+func (q *Queries) DeleteSentMails(ctx context.Context, sentAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSentMails, sentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserMails = `-- name: DeleteUserMails :exec
+DELETE FROM mail_outbox
+WHERE recipient = ANY($1::varchar[]) OR data->>'NewEmail' = ANY($1::varchar[])
+`
+
+// письма удаленному пользователю: в адресе и данных (NewEmail) его почта.
+// неотправленные тоже удаляются, адресата больше нет
+func (q *Queries) DeleteUserMails(ctx context.Context, emails []string) error {
+	_, err := q.db.Exec(ctx, deleteUserMails, emails)
+	return err
+}
+
+const enqueueMail = `-- name: EnqueueMail :one
+INSERT INTO mail_outbox(template, locale, recipient, data)
+VALUES ($1, $2, $3, $4)
+RETURNING id
+`
+
+type EnqueueMailParams struct {
+	Template  string
+	Locale    string
+	Recipient string
+	Data      []byte
+}
+
+func (q *Queries) EnqueueMail(ctx context.Context, arg EnqueueMailParams) (int64, error) {
+	row := q.db.QueryRow(ctx, enqueueMail,
+		arg.Template,
+		arg.Locale,
+		arg.Recipient,
+		arg.Data,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getOutboxStats = `-- name: GetOutboxStats :one
+SELECT
+    count(*) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL)::bigint AS pending,
+    count(*) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL AND last_error IS NOT NULL)::bigint AS retrying,
+    count(*) FILTER (WHERE dead_at IS NOT NULL)::bigint AS dead,
+    COALESCE(min(created_at) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL), now())::timestamptz AS oldest_pending_at
+FROM mail_outbox
+`
+
+type GetOutboxStatsRow struct {
+	Pending         int64
+	Retrying        int64
+	Dead            int64
+	OldestPendingAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetOutboxStats(ctx context.Context) (GetOutboxStatsRow, error) {
+	row := q.db.QueryRow(ctx, getOutboxStats)
+	var i GetOutboxStatsRow
+	err := row.Scan(
+		&i.Pending,
+		&i.Retrying,
+		&i.Dead,
+		&i.OldestPendingAt,
+	)
+	return i, err
+}
+
+const markMailDead = `-- name: MarkMailDead :exec
+UPDATE mail_outbox
+SET dead_at = now(), last_error = $2, data = NULL
+WHERE id = $1
+`
+
+type MarkMailDeadParams struct {
+	ID        int64
+	LastError pgtype.Text
+}
+
+func (q *Queries) MarkMailDead(ctx context.Context, arg MarkMailDeadParams) error {
+	_, err := q.db.Exec(ctx, markMailDead, arg.ID, arg.LastError)
+	return err
+}
+
+const markMailFailed = `-- name: MarkMailFailed :exec
+UPDATE mail_outbox
+SET next_attempt_at = $2, last_error = $3
+WHERE id = $1
+`
+
+type MarkMailFailedParams struct {
+	ID            int64
+	NextAttemptAt pgtype.Timestamptz
+	LastError     pgtype.Text
+}
+
+func (q *Queries) MarkMailFailed(ctx context.Context, arg MarkMailFailedParams) error {
+	_, err := q.db.Exec(ctx, markMailFailed, arg.ID, arg.NextAttemptAt, arg.LastError)
+	return err
+}
+
+const markMailSent = `-- name: MarkMailSent :exec
+UPDATE mail_outbox
+SET sent_at = now(), message_id = $2, last_error = NULL, data = NULL
+WHERE id = $1
+`
+
+type MarkMailSentParams struct {
+	ID        int64
+	MessageID pgtype.Text
+}
+
+// данные письма с токенами и ссылками после отправки или исчерпания попыток
+// больше не нужны и стираются, для разбора остаются адресат, шаблон и ошибка
+func (q *Queries) MarkMailSent(ctx context.Context, arg MarkMailSentParams) error {
+	_, err := q.db.Exec(ctx, markMailSent, arg.ID, arg.MessageID)
+	return err
+}
+
+const releaseMails = `-- name: ReleaseMails :exec
+UPDATE mail_outbox
+SET attempts = attempts - 1, next_attempt_at = now()
+WHERE id = ANY($1::bigint[]) AND sent_at IS NULL AND dead_at IS NULL
+`
+
+// письма, забранные, но не отправленные из-за остановки, возвращаются в очередь
+// без потраченной попытки, чтобы их сразу забрала другая реплика
+func (q *Queries) ReleaseMails(ctx context.Context, ids []int64) error {
+	_, err := q.db.Exec(ctx, releaseMails, ids)
+	return err
+}
@@ -0,0 +1,64 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: ratelimit.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteStaleRateLimits = `-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE tat < now()
+`
+
+func (q *Queries) DeleteStaleRateLimits(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleRateLimits)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getRateLimitTat = `-- name: GetRateLimitTat :one
+SELECT tat
+FROM rate_limits
+WHERE key = $1
+`
+
+func (q *Queries) GetRateLimitTat(ctx context.Context, key string) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getRateLimitTat, key)
+	var tat pgtype.Timestamptz
+	err := row.Scan(&tat)
+	return tat, err
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits(key, tat)
+VALUES ($1, now() + make_interval(secs => $2::float8))
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(rate_limits.tat, now()) + make_interval(secs => $2::float8)
+WHERE GREATEST(rate_limits.tat, now()) + make_interval(secs => $2::float8)
+    <= now() + make_interval(secs => $3::float8)
+RETURNING tat
+`
+
+type TakeRateLimitTokenParams struct {
+	Key           string
+	IntervalSecs  float64
+	ToleranceSecs float64
+}
+
+// токен забирается, только если после этого ведро не переполнится,
+// иначе строка не меняется и запрос ничего не возвращает.
+// interval - время восстановления одного токена, tolerance - interval * burst
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.Key, arg.IntervalSecs, arg.ToleranceSecs)
+	var tat pgtype.Timestamptz
+	err := row.Scan(&tat)
+	return tat, err
+}
@@ -0,0 +1,187 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: roles.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addRoleAssignment = `-- name: AddRoleAssignment :exec
+INSERT INTO role_assignments(user_id, role_id, action, actor_id)
+VALUES ($1, $2, $3, $4)
+`
+
+type AddRoleAssignmentParams struct {
+	UserID  string
+	RoleID  string
+	Action  string
+	ActorID pgtype.Text
+}
+
+func (q *Queries) AddRoleAssignment(ctx context.Context, arg AddRoleAssignmentParams) error {
+	_, err := q.db.Exec(ctx, addRoleAssignment,
+		arg.UserID,
+		arg.RoleID,
+		arg.Action,
+		arg.ActorID,
+	)
+	return err
+}
+
+const getRoleHoldersForUpdate = `-- name: GetRoleHoldersForUpdate :many
+SELECT user_id
+FROM user_roles
+WHERE role_id = $1
+ORDER BY user_id
+FOR UPDATE
+`
+
+// блокируются все обладатели роли, чтобы два параллельных разжалования
+// не оставили магазин без core админа. порядок блокировки фиксирован,
+// иначе две транзакции могут захватить строки навстречу друг другу
+func (q *Queries) GetRoleHoldersForUpdate(ctx context.Context, roleID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getRoleHoldersForUpdate, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserRoles = `-- name: GetUserRoles :many
+SELECT role_id
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role_id
+`
+
+func (q *Queries) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getUserRoles, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var role_id string
+		if err := rows.Scan(&role_id); err != nil {
+			return nil, err
+		}
+		items = append(items, role_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const grantRole = `-- name: GrantRole :exec
+INSERT INTO user_roles(user_id, role_id, granted_by)
+VALUES ($1, $2, $3)
+`
+
+type GrantRoleParams struct {
+	UserID    string
+	RoleID    string
+	GrantedBy pgtype.Text
+}
+
+func (q *Queries) GrantRole(ctx context.Context, arg GrantRoleParams) error {
+	_, err := q.db.Exec(ctx, grantRole, arg.UserID, arg.RoleID, arg.GrantedBy)
+	return err
+}
+
+const hasPermission = `-- name: HasPermission :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_roles
+        JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+    WHERE user_roles.user_id = $1 AND role_permissions.permission = $2
+)
+`
+
+type HasPermissionParams struct {
+	UserID     string
+	Permission string
+}
+
+func (q *Queries) HasPermission(ctx context.Context, arg HasPermissionParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasPermission, arg.UserID, arg.Permission)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const lockUser = `-- name: LockUser :one
+SELECT id
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+// блокировка строки пользователя сериализует изменения его ролей
+func (q *Queries) LockUser(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, lockUser, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const revokeAllRoles = `-- name: RevokeAllRoles :many
+DELETE FROM user_roles
+WHERE user_id = $1
+RETURNING role_id
+`
+
+func (q *Queries) RevokeAllRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, revokeAllRoles, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var role_id string
+		if err := rows.Scan(&role_id); err != nil {
+			return nil, err
+		}
+		items = append(items, role_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeRole = `-- name: RevokeRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2
+`
+
+type RevokeRoleParams struct {
+	UserID string
+	RoleID string
+}
+
+func (q *Queries) RevokeRole(ctx context.Context, arg RevokeRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeRole, arg.UserID, arg.RoleID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -10,4 +10,8 @@ const (
 var (
 	ErrAlreadyExists = errors.New("aslready exist")
 	ErrNotFound      = errors.New("no result found")
+	ErrLoginBlocked  = errors.New("login is blocked")
+
+	ErrHasHigherRole = errors.New("user has a higher role that must be removed first")
+	ErrLastCoreAdmin = errors.New("the last core admin can't be demoted or deleted")
 )
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// пустая строка хранится как NULL
+func nullableText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// журнал аудита только дополняется, и AnonymizeUser не может стереть из него почту,
+// поэтому туда пишется маска: первый символ и домен, "i***@example.com"
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+// символы шаблона LIKE в поисковой строке должны искаться как есть,
+// обратный слэш - экранирующий символ LIKE в postgres по умолчанию
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
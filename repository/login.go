@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// счетчики для почты (IP пустой) и для пары IP + почта
+func (r *Repository) GetLoginFailures(ctx context.Context, email, ip string) ([]models.LoginFailures, error) {
+	rows, err := r.q.GetLoginFailures(ctx, db.GetLoginFailuresParams{Email: email, Ip: ip})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.LoginFailures, 0, len(rows))
+	for _, f := range rows {
+		res = append(res, models.LoginFailures{
+			Email:        f.Email,
+			IP:           f.Ip,
+			Failures:     int(f.Failures),
+			BlockedUntil: f.BlockedUntil.Time,
+			IsLocked:     f.Locked,
+		})
+	}
+	return res, nil
+}
+
+// засчитывает попытку входа и сразу выставляет паузу перед следующей по правилам p.
+// ErrLoginBlocked - пауза или блокировка еще действует, попытка не засчитана
+func (r *Repository) ClaimLoginAttempt(ctx context.Context, email, ip string, p models.LoginPolicy) (models.LoginAttempt, error) {
+	row, err := r.q.ClaimLoginAttempt(ctx, db.ClaimLoginAttemptParams{
+		Email:           email,
+		Ip:              ip,
+		WindowStart:     pgtype.Timestamptz{Time: time.Now().Add(-p.Window), Valid: true},
+		LockThreshold:   int32(p.LockThreshold),
+		LockSecs:        p.LockDuration.Seconds(),
+		FreeAttempts:    int32(p.FreeAttempts),
+		BackoffBaseSecs: p.BackoffBase.Seconds(),
+		BackoffMaxSecs:  p.BackoffMax.Seconds(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.LoginAttempt{}, ErrLoginBlocked
+		}
+		return models.LoginAttempt{}, err
+	}
+	return models.LoginAttempt{Failures: int(row.Failures), Locked: row.Locked}, nil
+}
+
+// сбрасывает счетчик почты и пары IP + почта, с пустым ip - только счетчик почты
+func (r *Repository) ResetLoginFailures(ctx context.Context, email, ip string) error {
+	return r.q.ResetLoginFailures(ctx, db.ResetLoginFailuresParams{Email: email, Ip: ip})
+}
+
+func (r *Repository) DeleteStaleLoginFailures(ctx context.Context, olderThan time.Time) (int64, error) {
+	return r.q.DeleteStaleLoginFailures(ctx, pgtype.Timestamptz{Time: olderThan, Valid: true})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// токен и письмо с ним пишутся в одной транзакции: письмо не уйдет без токена,
+// а токен не останется без письма. ErrAlreadyExists - действующий токен с тем же
+// ключом уже есть, письмо не ставится в очередь
+func (r *Repository) EnqueueTokenMail(ctx context.Context, t models.MailToken, m models.OutboxMail) (int64, error) {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	if err = addMailToken(ctx, qtx, t); err != nil {
+		return 0, err
+	}
+	id, err := enqueueMail(ctx, qtx, m)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// q может быть в транзакции, см. CreateUser
+func addMailToken(ctx context.Context, q *db.Queries, t models.MailToken) error {
+	n, err := q.AddMailToken(ctx, db.AddMailTokenParams{
+		Key:       t.Key,
+		UserID:    t.UserID,
+		TokenHash: t.Hash,
+		ExpiresAt: pgtype.Timestamptz{Time: t.ExpiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+// токен не удаляется, см. mail.ValidResetToken
+func (r *Repository) MailTokenValid(ctx context.Context, key, hash string) (bool, error) {
+	return r.q.MailTokenValid(ctx, db.MailTokenValidParams{Key: key, TokenHash: hash})
+}
+
+// false - токена нет, он истек или уже использован
+func (r *Repository) UseMailToken(ctx context.Context, key, hash string) (bool, error) {
+	n, err := r.q.UseMailToken(ctx, db.UseMailTokenParams{Key: key, TokenHash: hash})
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *Repository) DeleteMailToken(ctx context.Context, key string) error {
+	return r.q.DeleteMailToken(ctx, key)
+}
+
+func (r *Repository) DeleteExpiredMailTokens(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredMailTokens(ctx)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glekoz/online-shop_user/shared/models"
+)
+
+func TestMailTokens(t *testing.T) {
+	r := newTestRepository(t, "20251016180133_initial.sql", "20251114120000_mail_outbox.sql", "20251116120000_mail_tokens.sql")
+	ctx := context.Background()
+	_, err := r.p.Exec(ctx, "INSERT INTO users(id, name, email, password) VALUES ('u1', 'Ivan', 'ivan@example.com', 'hash')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := models.OutboxMail{Template: "confirmation", Locale: "ru", To: "ivan@example.com", Data: []byte(`{}`)}
+	token := func(key, hash string, ttl time.Duration) models.MailToken {
+		return models.MailToken{Key: key, UserID: "u1", Hash: hash, ExpiresAt: time.Now().Add(ttl)}
+	}
+	pending := func() int {
+		s, err := r.GetOutboxStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Pending
+	}
+
+	t.Run("single use", func(t *testing.T) {
+		if _, err := r.EnqueueTokenMail(ctx, token("confirm", "h1", time.Hour), mail); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.UseMailToken(ctx, "confirm", "wrong"); err != nil || ok {
+			t.Fatalf("wrong hash: %t, %v", ok, err)
+		}
+		if ok, err := r.UseMailToken(ctx, "confirm", "h1"); err != nil || !ok {
+			t.Fatalf("first use: %t, %v", ok, err)
+		}
+		if ok, err := r.UseMailToken(ctx, "confirm", "h1"); err != nil || ok {
+			t.Fatalf("second use: %t, %v", ok, err)
+		}
+	})
+
+	t.Run("live token blocks a new mail", func(t *testing.T) {
+		before := pending()
+		if _, err := r.EnqueueTokenMail(ctx, token("reset", "h1", time.Hour), mail); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.EnqueueTokenMail(ctx, token("reset", "h2", time.Hour), mail); !errors.Is(err, ErrAlreadyExists) {
+			t.Fatalf("%v, want ErrAlreadyExists", err)
+		}
+		// письмо без токена не попадает в очередь
+		if n := pending() - before; n != 1 {
+			t.Errorf("%d mails queued, want 1", n)
+		}
+		if ok, err := r.MailTokenValid(ctx, "reset", "h1"); err != nil || !ok {
+			t.Fatalf("valid check: %t, %v", ok, err)
+		}
+		// проверка не удаляет токен сброса
+		if ok, err := r.MailTokenValid(ctx, "reset", "h1"); err != nil || !ok {
+			t.Fatalf("second valid check: %t, %v", ok, err)
+		}
+		if err := r.DeleteMailToken(ctx, "reset"); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.MailTokenValid(ctx, "reset", "h1"); err != nil || ok {
+			t.Fatalf("deleted token: %t, %v", ok, err)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		if _, err := r.EnqueueTokenMail(ctx, token("unlock", "h1", -time.Minute), mail); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.UseMailToken(ctx, "unlock", "h1"); err != nil || ok {
+			t.Fatalf("expired token is used: %t, %v", ok, err)
+		}
+		// истекший токен заменяется новым
+		if _, err := r.EnqueueTokenMail(ctx, token("unlock", "h2", time.Hour), mail); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.UseMailToken(ctx, "unlock", "h2"); err != nil || !ok {
+			t.Fatalf("new token: %t, %v", ok, err)
+		}
+		if _, err := r.EnqueueTokenMail(ctx, token("stale", "h1", -time.Minute), mail); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := r.DeleteExpiredMailTokens(ctx); err != nil || n != 1 {
+			t.Errorf("deleted %d expired tokens, %v", n, err)
+		}
+	})
+}
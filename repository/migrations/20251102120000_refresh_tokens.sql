@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY, -- sha256 от токена в hex, сам токен не храним
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id VARCHAR(50) NOT NULL, -- все токены, полученные ротацией от одного логина
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at TIMESTAMPTZ, -- токен уже обменян на новый, повторное использование - признак кражи
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_idx ON refresh_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX refresh_tokens_user_idx;
+DROP INDEX refresh_tokens_family_idx;
+DROP TABLE refresh_tokens;
+-- +goose StatementEnd
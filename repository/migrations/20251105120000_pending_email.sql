@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- новая почта хранится здесь, пока пользователь не перейдет по ссылке из письма,
+-- уникальность проверяется только при переносе в email
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(100);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN pending_email;
+-- +goose StatementEnd
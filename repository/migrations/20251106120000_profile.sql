@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN birthday DATE,
+    ADD COLUMN phone VARCHAR(20), -- в формате E.164
+    ADD COLUMN shipping_country VARCHAR(60), -- адрес доставки по умолчанию
+    ADD COLUMN shipping_city VARCHAR(100),
+    ADD COLUMN shipping_street VARCHAR(200),
+    ADD COLUMN shipping_postal_code VARCHAR(20);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN birthday,
+    DROP COLUMN phone,
+    DROP COLUMN shipping_country,
+    DROP COLUMN shipping_city,
+    DROP COLUMN shipping_street,
+    DROP COLUMN shipping_postal_code;
+-- +goose StatementEnd
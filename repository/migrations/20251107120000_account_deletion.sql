@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- строки пользователей не удаляются, чтобы id оставался стабильным для заказов
+-- в других сервисах, вместо этого персональные данные затираются
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at TIMESTAMPTZ, -- когда будет удален, вход в аккаунт отменяет удаление
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX users_deletion_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_deletion_idx;
+ALTER TABLE users
+    DROP COLUMN deletion_scheduled_at,
+    DROP COLUMN deleted_at;
+-- +goose StatementEnd
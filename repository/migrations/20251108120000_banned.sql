@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- бан выставляет модерация, другие сервисы узнают о нем через CheckPermissions
+ALTER TABLE users
+    ADD COLUMN banned_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN banned_at;
+-- +goose StatementEnd
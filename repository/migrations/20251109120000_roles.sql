@@ -0,0 +1,97 @@
+-- +goose Up
+-- +goose StatementBegin
+-- роли и права вместо таблиц admins и moders
+CREATE TABLE roles (
+    id VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+-- права вида "объект:действие", например comments:delete
+CREATE TABLE role_permissions (
+    role_id VARCHAR(50) NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id VARCHAR(50) NOT NULL REFERENCES roles(id),
+    granted_by VARCHAR(50), -- NULL, если роль выдана миграцией
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX user_roles_role_idx ON user_roles (role_id);
+
+-- история не ссылается на users, чтобы пережить удаление строк
+CREATE TABLE role_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    role_id VARCHAR(50) NOT NULL,
+    action VARCHAR(10) NOT NULL, -- grant или revoke
+    actor_id VARCHAR(50), -- NULL, если действие выполнено системой
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX role_assignments_user_idx ON role_assignments (user_id, created_at);
+
+INSERT INTO roles(id, description) VALUES
+    ('moder', 'чистит комментарии'),
+    ('admin', 'следит за магазином и назначает модераторов'),
+    ('core_admin', 'назначает и разжалует админов');
+
+INSERT INTO role_permissions(role_id, permission) VALUES
+    ('moder', 'comments:delete'),
+    ('admin', 'comments:delete'),
+    ('admin', 'users:read'),
+    ('admin', 'users:delete'),
+    ('admin', 'moders:manage'),
+    ('core_admin', 'comments:delete'),
+    ('core_admin', 'users:read'),
+    ('core_admin', 'users:delete'),
+    ('core_admin', 'moders:manage'),
+    ('core_admin', 'admins:manage'),
+    ('core_admin', 'core_admins:manage');
+
+INSERT INTO user_roles(user_id, role_id)
+SELECT id, 'moder' FROM moders;
+INSERT INTO user_roles(user_id, role_id)
+SELECT id, 'admin' FROM admins;
+-- младшие роли хранятся явно, иначе у админа нельзя было бы проверить
+-- и снять права модератора. админ мог быть и в moders
+INSERT INTO user_roles(user_id, role_id)
+SELECT id, 'moder' FROM admins
+ON CONFLICT DO NOTHING;
+INSERT INTO user_roles(user_id, role_id)
+SELECT id, 'core_admin' FROM admins WHERE is_core;
+
+INSERT INTO role_assignments(user_id, role_id, action)
+SELECT user_id, role_id, 'grant' FROM user_roles;
+
+DROP TABLE admins;
+DROP TABLE moders;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE admins (
+    id VARCHAR(50) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    is_core BOOLEAN NOT NULL
+);
+
+CREATE TABLE moders (
+    id VARCHAR(50) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO moders(id)
+SELECT user_id FROM user_roles WHERE role_id = 'moder';
+INSERT INTO admins(id, is_core)
+SELECT user_id, EXISTS (
+    SELECT 1 FROM user_roles core WHERE core.user_id = user_roles.user_id AND core.role_id = 'core_admin'
+) FROM user_roles WHERE role_id = 'admin';
+
+DROP TABLE role_assignments;
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE roles;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- журнал пишется в той же транзакции, что и само изменение.
+-- ссылок на users нет, чтобы записи пережили удаление пользователя
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id VARCHAR(50), -- NULL, если действие выполнено системой
+    target_id VARCHAR(50),
+    action VARCHAR(50) NOT NULL,
+    ip VARCHAR(64),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_target_idx ON audit_log (target_id, id);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, id);
+CREATE INDEX audit_log_action_idx ON audit_log (action, id);
+CREATE INDEX audit_log_created_idx ON audit_log (created_at);
+
+-- журнал только дополняется
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO role_permissions(role_id, permission) VALUES
+    ('admin', 'audit:read'),
+    ('core_admin', 'audit:read');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM role_permissions WHERE permission = 'audit:read';
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_append_only();
+-- +goose StatementEnd
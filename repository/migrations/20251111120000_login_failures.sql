@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- неудачные попытки входа. строка с пустым ip считает попытки для почты
+-- с любых адресов, остальные - для пары адрес + почта.
+-- почта не ссылается на users, чтобы по ответу нельзя было понять, есть ли аккаунт
+CREATE TABLE login_failures (
+    email VARCHAR(100) NOT NULL,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    failures INT NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    blocked_until TIMESTAMPTZ, -- до этого времени вход не проверяется
+    locked BOOLEAN NOT NULL DEFAULT FALSE, -- блокировка аккаунта, снимается ссылкой из письма
+    PRIMARY KEY (email, ip)
+);
+
+CREATE INDEX login_failures_last_idx ON login_failures (last_failure_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- общие для всех реплик ведра rate limiter'а в виде GCRA:
+-- tat - время, когда ведро снова станет полным. строка с tat в прошлом
+-- равнозначна отсутствию строки, поэтому такие строки можно удалять
+CREATE TABLE rate_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_limits_tat_idx ON rate_limits (tat);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE rate_limits;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- язык писем пользователя, см. models.Locales
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT 'ru';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN locale;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- письма, ожидающие отправки. пишутся в одной транзакции с изменением,
+-- о котором сообщают, отправляются воркерами из app.RunMailOutbox.
+-- письмо с sent_at отправлено, с dead_at - исчерпало попытки
+CREATE TABLE mail_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    template VARCHAR(50) NOT NULL,
+    locale VARCHAR(8) NOT NULL,
+    recipient VARCHAR(100) NOT NULL,
+    data JSONB, -- mail.TemplateData, NULL после отправки или исчерпания попыток
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    message_id VARCHAR(255), -- id письма у почтового провайдера
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ,
+    dead_at TIMESTAMPTZ
+);
+
+CREATE INDEX mail_outbox_pending_idx ON mail_outbox (next_attempt_at)
+    WHERE sent_at IS NULL AND dead_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mail_outbox;
+-- +goose StatementEnd
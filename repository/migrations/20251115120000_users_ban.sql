@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- бан выставляют модераторы и админы через BanUser, раньше banned_at
+-- некому было заполнить
+INSERT INTO role_permissions(role_id, permission) VALUES
+    ('moder', 'users:ban'),
+    ('admin', 'users:ban'),
+    ('core_admin', 'users:ban');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM role_permissions WHERE permission = 'users:ban';
+-- +goose StatementEnd
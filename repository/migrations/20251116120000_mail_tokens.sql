@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- токены из писем (подтверждение почты, сброс пароля, разблокировка, смена почты).
+-- пишутся в одной транзакции с письмом в mail_outbox, поэтому ссылка из письма
+-- работает после перезапуска и на любой реплике. хранится только хэш токена.
+-- key - назначение токена и его владелец, см. ключи в пакете mail
+CREATE TABLE mail_tokens (
+    key VARCHAR(200) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX mail_tokens_user_id_idx ON mail_tokens (user_id);
+CREATE INDEX mail_tokens_expires_at_idx ON mail_tokens (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mail_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (r *Repository) EnqueueMail(ctx context.Context, m models.OutboxMail) (int64, error) {
+	return enqueueMail(ctx, r.q, m)
+}
+
+// q может быть в транзакции, см. CreateUser
+func enqueueMail(ctx context.Context, q *db.Queries, m models.OutboxMail) (int64, error) {
+	return q.EnqueueMail(ctx, db.EnqueueMailParams{
+		Template:  m.Template,
+		Locale:    m.Locale,
+		Recipient: m.To,
+		Data:      m.Data,
+	})
+}
+
+// забранные письма не выдаются повторно до окончания lease
+func (r *Repository) ClaimMails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error) {
+	rows, err := r.q.ClaimMails(ctx, db.ClaimMailsParams{
+		LeaseSecs: lease.Seconds(),
+		MaxRows:   int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.OutboxMail, 0, len(rows))
+	for _, m := range rows {
+		res = append(res, models.OutboxMail{
+			ID:       m.ID,
+			Template: m.Template,
+			Locale:   m.Locale,
+			To:       m.Recipient,
+			Data:     m.Data,
+			Attempts: int(m.Attempts),
+		})
+	}
+	return res, nil
+}
+
+func (r *Repository) ReleaseMails(ctx context.Context, ids []int64) error {
+	return r.q.ReleaseMails(ctx, ids)
+}
+
+func (r *Repository) MarkMailSent(ctx context.Context, id int64, messageID string) error {
+	return r.q.MarkMailSent(ctx, db.MarkMailSentParams{ID: id, MessageID: nullableText(messageID)})
+}
+
+func (r *Repository) MarkMailFailed(ctx context.Context, id int64, next time.Time, reason string) error {
+	return r.q.MarkMailFailed(ctx, db.MarkMailFailedParams{
+		ID:            id,
+		NextAttemptAt: pgtype.Timestamptz{Time: next, Valid: true},
+		LastError:     nullableText(reason),
+	})
+}
+
+func (r *Repository) MarkMailDead(ctx context.Context, id int64, reason string) error {
+	return r.q.MarkMailDead(ctx, db.MarkMailDeadParams{ID: id, LastError: nullableText(reason)})
+}
+
+func (r *Repository) GetOutboxStats(ctx context.Context) (models.OutboxStats, error) {
+	s, err := r.q.GetOutboxStats(ctx)
+	if err != nil {
+		return models.OutboxStats{}, err
+	}
+	return models.OutboxStats{
+		Pending:       int(s.Pending),
+		Retrying:      int(s.Retrying),
+		Dead:          int(s.Dead),
+		OldestPending: time.Since(s.OldestPendingAt.Time),
+	}, nil
+}
+
+func (r *Repository) DeleteSentMails(ctx context.Context, olderThan time.Time) (int64, error) {
+	return r.q.DeleteSentMails(ctx, pgtype.Timestamptz{Time: olderThan, Valid: true})
+}
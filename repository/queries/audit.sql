@@ -0,0 +1,17 @@
+-- name: AddAuditEntry :exec
+INSERT INTO audit_log(actor_id, target_id, action, ip, before, after)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- пустые фильтры не применяются, страницы идут от новых записей к старым,
+-- before_id - id последней записи предыдущей страницы
+-- name: GetAuditLog :many
+SELECT *
+FROM audit_log
+WHERE (sqlc.narg(target_id)::varchar IS NULL OR target_id = sqlc.narg(target_id))
+    AND (sqlc.narg(actor_id)::varchar IS NULL OR actor_id = sqlc.narg(actor_id))
+    AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+    AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+    AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+    AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT @max_rows;
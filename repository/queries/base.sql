@@ -1,96 +1,222 @@
 -- name: CreateUser :exec
-INSERT INTO users(id, name, email, password)
-VALUES ($1, $2, $3, $4);
-
--- name: PromoteModer :exec
-INSERT INTO moders(id)
-VALUES($1);
-
--- при назначении админом не забыть в транзакции дать права модератора
--- name: PromoteAdmin :exec
-INSERT INTO admins(id, isCore)
-VALUES($1, FALSE);
-
--- name: PromoteCoreAdmin :execrows
-UPDATE admins
-SET isCore = TRUE
-WHERE id = $1;
+INSERT INTO users(id, name, email, password, locale)
+VALUES ($1, $2, $3, $4, $5);
 
+-- удаленные (анонимизированные) пользователи в запросах ниже считаются
+-- несуществующими, иначе по старой ссылке из письма можно было бы вернуть
+-- анонимизированной записи пароль и войти в нее
 -- name: GetUserByID :one
 SELECT * 
 FROM users
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- используется при логине (инфа добавляется в токен), поэтому 
--- нужна дополнительная информация о правах (модер, админ, isCore),
+-- нужен список прав пользователя,
 -- чтобы при каждом GET запросе не идти в БД
 -- name: GetUserByEmail :one
-SELECT users.id, users.name, users.password,
-    CASE WHEN moders.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_moder, 
-    CASE WHEN admins.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_admin,
-    CASE WHEN admins.iscore IS NOT NULL THEN admins.iscore ELSE FALSE END AS is_core
+SELECT users.id, users.name, users.password, users.locale,
+    (users.deletion_scheduled_at IS NOT NULL)::boolean AS deletion_scheduled,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
+FROM users
+WHERE users.email = $1 AND users.deleted_at IS NULL;
+
+-- то же, что GetUserByEmail, но для перевыпуска токенов по рефреш токену,
+-- чтобы в новые токены попадали актуальные имя и права
+-- name: GetUserTokenByID :one
+SELECT users.id, users.name,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
 FROM users
-    LEFT JOIN moders ON users.id = moders.id
-    LEFT JOIN admins ON users.id = admins.id
-WHERE users.email = $1;
+WHERE users.id = $1 AND users.deleted_at IS NULL;
 
 -- этот метод вызывается только администратором,
--- поэтому нужна полная инфоормация о правах (модератор, админ, isCore),
--- чтобы отобразить её в интерфейсе управления пользователями
+-- поэтому нужны роли пользователя,
+-- чтобы отобразить их в интерфейсе управления пользователями
 -- name: GetUsersByEmail :many
 SELECT users.id, users.name, users.email, users.email_confirmed,
-	CASE WHEN moders.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_moder, 
-	CASE WHEN admins.id IS NOT NULL THEN TRUE ELSE FALSE END AS is_admin,
-	CASE WHEN admins.iscore IS NOT NULL THEN admins.iscore ELSE FALSE END AS is_core
+    ARRAY(
+        SELECT user_roles.role_id
+        FROM user_roles
+        WHERE user_roles.user_id = users.id
+        ORDER BY user_roles.role_id
+    )::varchar[] AS roles
 FROM users 
-	LEFT JOIN moders ON users.id = moders.id
-	LEFT JOIN admins ON users.id = admins.id
-WHERE users.email LIKE $1;
-
--- name: GetModer :one
-SELECT * 
-FROM moders
-WHERE id = $1;
+WHERE users.email LIKE $1 AND users.deleted_at IS NULL;
 
--- name: GetAdmin :one
-SELECT * 
-FROM admins
-WHERE id = $1;
+-- права для других сервисов, удаленные пользователи считаются несуществующими
+-- name: GetPermissions :many
+SELECT users.id, users.email_confirmed,
+    (users.banned_at IS NOT NULL)::boolean AS is_banned,
+    ARRAY(
+        SELECT user_roles.role_id
+        FROM user_roles
+        WHERE user_roles.user_id = users.id
+        ORDER BY user_roles.role_id
+    )::varchar[] AS roles,
+    ARRAY(
+        SELECT DISTINCT role_permissions.permission
+        FROM user_roles
+            JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+        WHERE user_roles.user_id = users.id
+        ORDER BY role_permissions.permission
+    )::varchar[] AS permissions
+FROM users
+WHERE users.id = ANY(@ids::varchar[]) AND users.deleted_at IS NULL;
 
 -- name: ConfirmEmail :execrows
 UPDATE users
 SET email_confirmed = TRUE
 WHERE id = $1;
 
--- впоследствии этот метод надо расширить на день рождения и адрес
--- name: ChangeName :execrows
+-- частичное обновление профиля: меняются только поля с флагом set_*,
+-- NULL в значении очищает поле
+-- name: UpdateProfile :execrows
 UPDATE users
-SET name = $1
-WHERE id = $2;
+SET name = CASE WHEN @set_name::boolean THEN @name::varchar ELSE name END,
+    birthday = CASE WHEN @set_birthday::boolean THEN sqlc.narg(birthday)::date ELSE birthday END,
+    phone = CASE WHEN @set_phone::boolean THEN sqlc.narg(phone)::varchar ELSE phone END,
+    shipping_country = CASE WHEN @set_shipping_address::boolean THEN sqlc.narg(shipping_country)::varchar ELSE shipping_country END,
+    shipping_city = CASE WHEN @set_shipping_address::boolean THEN sqlc.narg(shipping_city)::varchar ELSE shipping_city END,
+    shipping_street = CASE WHEN @set_shipping_address::boolean THEN sqlc.narg(shipping_street)::varchar ELSE shipping_street END,
+    shipping_postal_code = CASE WHEN @set_shipping_address::boolean THEN sqlc.narg(shipping_postal_code)::varchar ELSE shipping_postal_code END,
+    locale = CASE WHEN @set_locale::boolean THEN @locale::varchar ELSE locale END
+WHERE id = @id;
+
+-- вызывается в одной транзакции с ChangePassword, до него
+-- name: SavePasswordToHistory :exec
+INSERT INTO password_history(user_id, password)
+SELECT users.id, users.password
+FROM users
+WHERE users.id = $1;
+
+-- текущий пароль в выборку не входит, он есть в users
+-- name: GetPasswordHistory :many
+SELECT password
+FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
 
 -- асинхронно с подтверждением через почту (ссылка на изменение пароля так же отправляется на почту, и на странице по этой ссылке можно сменить пароль)
 -- name: ChangePassword :execrows 
 UPDATE users
 SET password=$1
-WHERE id = $2;
+WHERE id = $2 AND deleted_at IS NULL;
 
--- асинхронно и не обновлять, пока новая почта не будет подтверждена
--- name: ChangeEmail :execrows 
+-- почта не обновляется, пока новая не будет подтверждена, до этого она лежит в pending_email
+-- name: SetPendingEmail :execrows
 UPDATE users
-SET email = $1
-WHERE id = $2;
+SET pending_email = $1
+WHERE id = $2 AND deleted_at IS NULL;
 
--- нужно проверять, чтобы было право администратора
--- name: DeleteUser :execrows
-DELETE FROM users
-WHERE id = $1;
+-- сравнение с pending_email не дает подтвердить адрес, который успели заменить
+-- после отправки письма; новая почта сразу считается подтвержденной - ссылка пришла на неё
+-- name: ConfirmEmailChange :execrows
+UPDATE users
+SET email = pending_email, pending_email = NULL, email_confirmed = TRUE
+WHERE id = $1 AND pending_email = $2 AND deleted_at IS NULL;
 
--- нужно проверять, чтобы права на модерацию не убрали у админа
--- name: DeleteModer :execrows
-DELETE FROM moders
-WHERE id = $1;
+-- was_banned - состояние до изменения, чтобы не писать в журнал повторный бан.
+-- время первого бана сохраняется
+-- name: SetBanned :one
+UPDATE users
+SET banned_at = CASE WHEN @banned::boolean THEN COALESCE(users.banned_at, now()) END
+FROM (
+    SELECT u.id, u.banned_at
+    FROM users AS u
+    WHERE u.id = @id AND u.deleted_at IS NULL
+    FOR UPDATE
+) AS prev
+WHERE users.id = prev.id
+RETURNING (prev.banned_at IS NOT NULL)::boolean AS was_banned;
+
+-- name: ScheduleDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = $1
+WHERE id = $2 AND deleted_at IS NULL;
+
+-- name: CancelDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL
+WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL;
 
--- нужно проверять, чтобы было isCore 
--- name: DeleteAdmin :execrows
-DELETE FROM admins
-WHERE id = $1;
\ No newline at end of file
+-- name: GetDueDeletions :many
+SELECT id
+FROM users
+WHERE deletion_scheduled_at <= now() AND deleted_at IS NULL
+ORDER BY deletion_scheduled_at
+LIMIT $1;
+
+-- почты удаляемого пользователя, чтобы затереть их в очереди писем и счетчиках входа
+-- name: GetUserEmailsForUpdate :one
+SELECT email, pending_email
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- вместо удаления строки затираются персональные данные, id остается.
+-- пустой пароль не совпадет ни с одним хэшем бкрипта, поэтому войти нельзя,
+-- а почта уникальна за счет id
+-- name: AnonymizeUser :execrows
+UPDATE users
+SET name = 'deleted user',
+    email = 'deleted-' || id || '@deleted.invalid',
+    password = '',
+    email_confirmed = FALSE,
+    pending_email = NULL,
+    birthday = NULL,
+    phone = NULL,
+    shipping_country = NULL,
+    shipping_city = NULL,
+    shipping_street = NULL,
+    shipping_postal_code = NULL,
+    deletion_scheduled_at = NULL,
+    deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: DeletePasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id = $1;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens(token_hash, user_id, family_id, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetRefreshToken :one
+SELECT *
+FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- условие на used_at и revoked_at защищает от гонки двух одновременных ротаций
+-- name: UseRefreshToken :execrows
+UPDATE refresh_tokens
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- старая почта нужна для журнала аудита (в маскированном виде)
+-- name: GetEmailForUpdate :one
+SELECT email
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
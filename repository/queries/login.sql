@@ -0,0 +1,42 @@
+-- name: GetLoginFailures :many
+SELECT *
+FROM login_failures
+WHERE email = $1 AND ip IN ('', $2);
+
+-- попытка засчитывается до проверки пароля, а успешный вход сбрасывает счетчик.
+-- проверка паузы, увеличение счетчика и новая пауза - один запрос под блокировкой строки,
+-- поэтому параллельные попытки не проходят все разом мимо проверки.
+-- пока действует пауза, строка не возвращается.
+-- счетчик начинается заново, если с прошлой попытки прошло больше окна.
+-- первая попытка всегда бесплатная (free_attempts >= 1), lock_threshold = 0 - без блокировки
+-- name: ClaimLoginAttempt :one
+INSERT INTO login_failures AS f (email, ip, failures)
+VALUES (sqlc.arg(email), sqlc.arg(ip), 1)
+ON CONFLICT (email, ip) DO UPDATE
+SET failures = CASE WHEN f.last_failure_at < sqlc.arg(window_start)::timestamptz THEN 1 ELSE f.failures + 1 END,
+    last_failure_at = now(),
+    locked = sqlc.arg(lock_threshold)::int > 0
+        AND (CASE WHEN f.last_failure_at < sqlc.arg(window_start)::timestamptz THEN 1 ELSE f.failures + 1 END) >= sqlc.arg(lock_threshold)::int,
+    blocked_until = CASE
+        WHEN sqlc.arg(lock_threshold)::int > 0
+            AND (CASE WHEN f.last_failure_at < sqlc.arg(window_start)::timestamptz THEN 1 ELSE f.failures + 1 END) >= sqlc.arg(lock_threshold)::int
+            THEN now() + make_interval(secs => sqlc.arg(lock_secs)::float8)
+        WHEN (CASE WHEN f.last_failure_at < sqlc.arg(window_start)::timestamptz THEN 1 ELSE f.failures + 1 END) > sqlc.arg(free_attempts)::int
+            THEN now() + make_interval(secs => LEAST(
+                sqlc.arg(backoff_base_secs)::float8 * power(2, LEAST((CASE WHEN f.last_failure_at < sqlc.arg(window_start)::timestamptz THEN 1 ELSE f.failures + 1 END) - sqlc.arg(free_attempts)::int - 1, 20)),
+                sqlc.arg(backoff_max_secs)::float8))
+    END
+WHERE f.blocked_until IS NULL OR f.blocked_until <= now()
+RETURNING failures, locked;
+
+-- name: ResetLoginFailures :exec
+DELETE FROM login_failures
+WHERE email = $1 AND ip IN ('', $2);
+
+-- name: DeleteUserLoginFailures :exec
+DELETE FROM login_failures
+WHERE email = ANY(@emails::varchar[]);
+
+-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until < now());
@@ -0,0 +1,31 @@
+-- истекший токен с тем же ключом заменяется, действующий остается:
+-- 0 строк - письмо с таким токеном уже отправлено
+-- name: AddMailToken :execrows
+INSERT INTO mail_tokens(key, user_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE
+SET user_id = EXCLUDED.user_id, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+WHERE mail_tokens.expires_at <= now();
+
+-- name: MailTokenValid :one
+SELECT EXISTS (
+    SELECT 1 FROM mail_tokens
+    WHERE key = $1 AND token_hash = $2 AND expires_at > now()
+);
+
+-- проверка и удаление одним запросом, чтобы токен нельзя было использовать дважды
+-- name: UseMailToken :execrows
+DELETE FROM mail_tokens
+WHERE key = $1 AND token_hash = $2 AND expires_at > now();
+
+-- name: DeleteMailToken :exec
+DELETE FROM mail_tokens
+WHERE key = $1;
+
+-- name: DeleteUserMailTokens :exec
+DELETE FROM mail_tokens
+WHERE user_id = $1;
+
+-- name: DeleteExpiredMailTokens :execrows
+DELETE FROM mail_tokens
+WHERE expires_at <= now();
@@ -0,0 +1,64 @@
+-- name: EnqueueMail :one
+INSERT INTO mail_outbox(template, locale, recipient, data)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- письма забираются с арендой: next_attempt_at сдвигается на lease, и если
+-- воркер упадет, письмо заберет другой после ее окончания.
+-- SKIP LOCKED не дает двум репликам забрать одно письмо
+-- name: ClaimMails :many
+UPDATE mail_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = now() + make_interval(secs => @lease_secs::float8)
+WHERE id IN (
+    SELECT id
+    FROM mail_outbox
+    WHERE sent_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT @max_rows
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, template, locale, recipient, data, attempts;
+
+-- данные письма с токенами и ссылками после отправки или исчерпания попыток
+-- больше не нужны и стираются, для разбора остаются адресат, шаблон и ошибка
+-- name: MarkMailSent :exec
+UPDATE mail_outbox
+SET sent_at = now(), message_id = $2, last_error = NULL, data = NULL
+WHERE id = $1;
+
+-- письма, забранные, но не отправленные из-за остановки, возвращаются в очередь
+-- без потраченной попытки, чтобы их сразу забрала другая реплика
+-- name: ReleaseMails :exec
+UPDATE mail_outbox
+SET attempts = attempts - 1, next_attempt_at = now()
+WHERE id = ANY(@ids::bigint[]) AND sent_at IS NULL AND dead_at IS NULL;
+
+-- name: MarkMailFailed :exec
+UPDATE mail_outbox
+SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: MarkMailDead :exec
+UPDATE mail_outbox
+SET dead_at = now(), last_error = $2, data = NULL
+WHERE id = $1;
+
+-- письма удаленному пользователю: в адресе и данных (NewEmail) его почта.
+-- неотправленные тоже удаляются, адресата больше нет
+-- name: DeleteUserMails :exec
+DELETE FROM mail_outbox
+WHERE recipient = ANY(@emails::varchar[]) OR data->>'NewEmail' = ANY(@emails::varchar[]);
+
+-- name: GetOutboxStats :one
+SELECT
+    count(*) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL)::bigint AS pending,
+    count(*) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL AND last_error IS NOT NULL)::bigint AS retrying,
+    count(*) FILTER (WHERE dead_at IS NOT NULL)::bigint AS dead,
+    COALESCE(min(created_at) FILTER (WHERE sent_at IS NULL AND dead_at IS NULL), now())::timestamptz AS oldest_pending_at
+FROM mail_outbox;
+
+-- недоставленные письма остаются для разбора
+-- name: DeleteSentMails :execrows
+DELETE FROM mail_outbox
+WHERE sent_at < $1;
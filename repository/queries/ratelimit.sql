@@ -0,0 +1,20 @@
+-- токен забирается, только если после этого ведро не переполнится,
+-- иначе строка не меняется и запрос ничего не возвращает.
+-- interval - время восстановления одного токена, tolerance - interval * burst
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits(key, tat)
+VALUES (@key, now() + make_interval(secs => @interval_secs::float8))
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(rate_limits.tat, now()) + make_interval(secs => @interval_secs::float8)
+WHERE GREATEST(rate_limits.tat, now()) + make_interval(secs => @interval_secs::float8)
+    <= now() + make_interval(secs => @tolerance_secs::float8)
+RETURNING tat;
+
+-- name: GetRateLimitTat :one
+SELECT tat
+FROM rate_limits
+WHERE key = $1;
+
+-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE tat < now();
@@ -0,0 +1,47 @@
+-- блокировка строки пользователя сериализует изменения его ролей
+-- name: LockUser :one
+SELECT id
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: GetUserRoles :many
+SELECT role_id
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role_id;
+
+-- name: HasPermission :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_roles
+        JOIN role_permissions ON user_roles.role_id = role_permissions.role_id
+    WHERE user_roles.user_id = $1 AND role_permissions.permission = $2
+);
+
+-- name: GrantRole :exec
+INSERT INTO user_roles(user_id, role_id, granted_by)
+VALUES ($1, $2, $3);
+
+-- name: RevokeRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
+
+-- name: RevokeAllRoles :many
+DELETE FROM user_roles
+WHERE user_id = $1
+RETURNING role_id;
+
+-- блокируются все обладатели роли, чтобы два параллельных разжалования
+-- не оставили магазин без core админа. порядок блокировки фиксирован,
+-- иначе две транзакции могут захватить строки навстречу друг другу
+-- name: GetRoleHoldersForUpdate :many
+SELECT user_id
+FROM user_roles
+WHERE role_id = $1
+ORDER BY user_id
+FOR UPDATE;
+
+-- name: AddRoleAssignment :exec
+INSERT INTO role_assignments(user_id, role_id, action, actor_id)
+VALUES ($1, $2, $3, $4);
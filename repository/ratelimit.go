@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/time/rate"
+)
+
+var ErrBadRateLimit = errors.New("rate limit must be positive")
+
+// длина rate_limits.key
+const maxRateLimitKeyLen = 255
+
+// ключ содержит данные из запроса, и слишком длинный ключ не должен ломать
+// вставку, иначе лимит обходился бы длиной значения. такие ключи хэшируются,
+// короткие остаются читаемыми
+func rateLimitKey(key string) string {
+	if len(key) <= maxRateLimitKeyLen {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// реализация handler.LimiterStore, общая для всех реплик сервиса.
+// возвращает 0, если токен забран, иначе время, через которое он появится
+func (r *Repository) TakeToken(ctx context.Context, key string, limit rate.Limit, burst int) (time.Duration, error) {
+	if limit == rate.Inf {
+		return 0, nil
+	}
+	if limit <= 0 || burst <= 0 {
+		return 0, ErrBadRateLimit
+	}
+	key = rateLimitKey(key)
+	interval := 1 / float64(limit)
+	tolerance := interval * float64(burst)
+	_, err := r.q.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:           key,
+		IntervalSecs:  interval,
+		ToleranceSecs: tolerance,
+	})
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	// отказ: время ожидания считается отдельным запросом, поэтому оно приблизительное
+	intervalDur := time.Duration(interval * float64(time.Second))
+	tat, err := r.q.GetRateLimitTat(ctx, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return intervalDur, nil
+		}
+		return 0, err
+	}
+	return gcraRetryAfter(tat.Time, time.Now(), intervalDur, time.Duration(tolerance*float64(time.Second))), nil
+}
+
+// токен можно забрать, когда max(tat, now) + interval <= now + tolerance,
+// то есть с момента tat + interval - tolerance. если он уже наступил
+// (ведро успело освободиться между запросами), ждать нужно один интервал
+func gcraRetryAfter(tat, now time.Time, interval, tolerance time.Duration) time.Duration {
+	if d := tat.Add(interval - tolerance).Sub(now); d > 0 {
+		return d
+	}
+	return interval
+}
+
+func (r *Repository) DeleteStaleBuckets(ctx context.Context) error {
+	_, err := r.q.DeleteStaleRateLimits(ctx)
+	return err
+}
@@ -3,11 +3,16 @@ package repository
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
+	"github.com/glekoz/online-shop_user/config"
 	"github.com/glekoz/online-shop_user/repository/db"
 	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/glekoz/online-shop_user/tracing"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,8 +22,16 @@ type Repository struct {
 	p *pgxpool.Pool
 }
 
-func New(dsn string) (*Repository, error) {
-	pool, err := pgxpool.New(context.Background(), dsn)
+func New(cfg config.Database) (*Repository, error) {
+	pcfg, err := pgxpool.ParseConfig(string(cfg.DSN))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		pcfg.MaxConns = cfg.MaxConns
+	}
+	pcfg.ConnConfig.Tracer = tracing.PgxTracer{}
+	pool, err := pgxpool.NewWithConfig(context.Background(), pcfg)
 	if err != nil {
 		return nil, err
 	}
@@ -29,43 +42,25 @@ func New(dsn string) (*Repository, error) {
 	}, nil
 }
 
-func (r *Repository) CreateUser(ctx context.Context, id, name, email, hashedPassword string) error {
-	err := r.q.CreateUser(ctx, db.CreateUserParams{
-		ID:       id,
-		Name:     name,
-		Email:    email,
-		Password: hashedPassword,
-	})
-	if err != nil {
-		var errp *pgconn.PgError
-		if errors.As(err, &errp) {
-			if errp.Code == UniqueViolationCode {
-				return ErrAlreadyExists
-			}
-		}
-		return err
-	}
-	return nil
+// закрывает пул соединений, дожидаясь возврата всех соединений в пул
+func (r *Repository) Close() {
+	r.p.Close()
 }
 
-func (r *Repository) PromoteModer(ctx context.Context, id string) error {
-	err := r.q.PromoteModer(ctx, id)
-	if err != nil {
-		var errp *pgconn.PgError
-		if errors.As(err, &errp) {
-			switch errp.Code {
-			case ForeignKeyViolationCode:
-				return ErrNotFound
-			case UniqueViolationCode:
-				return ErrAlreadyExists
-			}
-		}
-		return err
-	}
-	return nil
+// для проверки готовности, берет соединение из пула
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.p.Ping(ctx)
+}
+
+// статистика пула для метрик
+func (r *Repository) Stat() *pgxpool.Stat {
+	return r.p.Stat()
 }
 
-func (r *Repository) PromoteAdmin(ctx context.Context, id string) error {
+// письмо с подтверждением почты и его токен пишутся в той же транзакции,
+// поэтому пользователь не останется без письма, если сервис упадет после вставки,
+// а ссылка из письма будет работать на любой реплике
+func (r *Repository) CreateUser(ctx context.Context, id, name, email, hashedPassword, locale string, token models.MailToken, confirmation models.OutboxMail) error {
 	tx, err := r.p.Begin(ctx)
 	if err != nil {
 		return err
@@ -73,44 +68,29 @@ func (r *Repository) PromoteAdmin(ctx context.Context, id string) error {
 	defer tx.Rollback(ctx)
 	qtx := r.q.WithTx(tx)
 
-	err = qtx.PromoteAdmin(ctx, id)
+	err = qtx.CreateUser(ctx, db.CreateUserParams{
+		ID:       id,
+		Name:     name,
+		Email:    email,
+		Password: hashedPassword,
+		Locale:   locale,
+	})
 	if err != nil {
 		var errp *pgconn.PgError
 		if errors.As(err, &errp) {
-			switch errp.Code {
-			case ForeignKeyViolationCode:
-				return ErrNotFound
-			case UniqueViolationCode:
+			if errp.Code == UniqueViolationCode {
 				return ErrAlreadyExists
 			}
 		}
 		return err
 	}
-
-	err = qtx.PromoteModer(ctx, id)
-	if err != nil {
-		var errp *pgconn.PgError
-		if errors.As(err, &errp) {
-			if errp.Code == UniqueViolationCode {
-				err = nil
-			}
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return tx.Commit(ctx)
-}
-
-func (r *Repository) PromoteCoreAdmin(ctx context.Context, id string) error {
-	num, err := r.q.PromoteCoreAdmin(ctx, id)
-	if err != nil {
+	if err = addMailToken(ctx, qtx, token); err != nil {
 		return err
 	}
-	if num != 1 {
-		return ErrNotFound
+	if _, err = enqueueMail(ctx, qtx, confirmation); err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit(ctx)
 }
 
 func (r *Repository) GetUserByID(ctx context.Context, id string) (models.User, error) {
@@ -126,6 +106,31 @@ func (r *Repository) GetUserByID(ctx context.Context, id string) (models.User, e
 		Name:             u.Name,
 		Email:            u.Email,
 		IsEmailConfirmed: u.EmailConfirmed,
+		PendingEmail:     u.PendingEmail.String,
+		Birthday:         u.Birthday.Time,
+		Phone:            u.Phone.String,
+		ShippingAddress: models.Address{
+			Country:    u.ShippingCountry.String,
+			City:       u.ShippingCity.String,
+			Street:     u.ShippingStreet.String,
+			PostalCode: u.ShippingPostalCode.String,
+		},
+		Locale: u.Locale,
+	}, nil
+}
+
+func (r *Repository) GetUserTokenByID(ctx context.Context, id string) (models.UserToken, error) {
+	u, err := r.q.GetUserTokenByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UserToken{}, ErrNotFound
+		}
+		return models.UserToken{}, err
+	}
+	return models.UserToken{
+		ID:          u.ID,
+		Name:        u.Name,
+		Permissions: u.Permissions,
 	}, nil
 }
 
@@ -138,17 +143,17 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (models.U
 		return models.UserTokenWithPassword{}, err
 	}
 	return models.UserTokenWithPassword{
-		ID:             u.ID,
-		Name:           u.Name,
-		HashedPassword: u.Password,
-		IsModer:        u.IsModer,
-		IsAdmin:        u.IsAdmin,
-		IsCore:         u.IsCore,
+		ID:                  u.ID,
+		Name:                u.Name,
+		HashedPassword:      u.Password,
+		IsDeletionScheduled: u.DeletionScheduled,
+		Permissions:         u.Permissions,
+		Locale:              u.Locale,
 	}, nil
 }
 
 func (r *Repository) GetUsersByEmail(ctx context.Context, email string) ([]models.UserInfo, error) {
-	us, err := r.q.GetUsersByEmail(ctx, email+"%")
+	us, err := r.q.GetUsersByEmail(ctx, likeEscaper.Replace(email)+"%")
 	if err != nil {
 		return nil, err
 	}
@@ -162,37 +167,30 @@ func (r *Repository) GetUsersByEmail(ctx context.Context, email string) ([]model
 			Name:             u.Name,
 			Email:            u.Email,
 			IsEmailConfirmed: u.EmailConfirmed,
-			IsModer:          u.IsModer,
-			IsAdmin:          u.IsAdmin,
-			IsCore:           u.IsCore,
+			Roles:            u.Roles,
 		})
 	}
 	return users, nil
 }
 
-func (r *Repository) GetModer(ctx context.Context, id string) (string, error) {
-	m, err := r.q.GetModer(ctx, id)
-	if err != nil { // if m == ""
-		if errors.Is(err, pgx.ErrNoRows) {
-			return "", ErrNotFound
-		}
-		return "", err
-	}
-	return m, nil
-}
-
-func (r *Repository) GetAdmin(ctx context.Context, id string) (models.Admin, error) {
-	a, err := r.q.GetAdmin(ctx, id)
+// пользователи, которых нет, в результат не попадают
+func (r *Repository) GetPermissions(ctx context.Context, ids []string) ([]models.Permissions, error) {
+	ps, err := r.q.GetPermissions(ctx, ids)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return models.Admin{}, ErrNotFound
-		}
-		return models.Admin{}, err
+		return nil, err
 	}
-	return models.Admin{
-		ID:     a.ID,
-		IsCore: a.IsCore,
-	}, nil
+	res := make([]models.Permissions, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, models.Permissions{
+			UserID:           p.ID,
+			Found:            true,
+			IsEmailConfirmed: p.EmailConfirmed,
+			IsBanned:         p.IsBanned,
+			Roles:            p.Roles,
+			Permissions:      p.Permissions,
+		})
+	}
+	return res, nil
 }
 
 func (r *Repository) ConfirmEmail(ctx context.Context, id string) error {
@@ -206,11 +204,32 @@ func (r *Repository) ConfirmEmail(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *Repository) ChangeName(ctx context.Context, id, newName string) error {
-	n, err := r.q.ChangeName(ctx, db.ChangeNameParams{
-		ID:   id,
-		Name: newName,
-	})
+func (r *Repository) UpdateProfile(ctx context.Context, id string, upd models.ProfileUpdate) error {
+	params := db.UpdateProfileParams{ID: id}
+	if upd.Name != nil {
+		params.SetName = true
+		params.Name = *upd.Name
+	}
+	if upd.Birthday != nil {
+		params.SetBirthday = true
+		params.Birthday = pgtype.Date{Time: *upd.Birthday, Valid: !upd.Birthday.IsZero()}
+	}
+	if upd.Phone != nil {
+		params.SetPhone = true
+		params.Phone = nullableText(*upd.Phone)
+	}
+	if upd.ShippingAddress != nil {
+		params.SetShippingAddress = true
+		params.ShippingCountry = nullableText(upd.ShippingAddress.Country)
+		params.ShippingCity = nullableText(upd.ShippingAddress.City)
+		params.ShippingStreet = nullableText(upd.ShippingAddress.Street)
+		params.ShippingPostalCode = nullableText(upd.ShippingAddress.PostalCode)
+	}
+	if upd.Locale != nil {
+		params.SetLocale = true
+		params.Locale = *upd.Locale
+	}
+	n, err := r.q.UpdateProfile(ctx, params)
 	if err != nil {
 		return err
 	}
@@ -220,8 +239,20 @@ func (r *Repository) ChangeName(ctx context.Context, id, newName string) error {
 	return nil
 }
 
+// старый пароль в той же транзакции уходит в историю
 func (r *Repository) ChangePassword(ctx context.Context, id, newHashedPassword string) error {
-	n, err := r.q.ChangePassword(ctx, db.ChangePasswordParams{
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	err = qtx.SavePasswordToHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := qtx.ChangePassword(ctx, db.ChangePasswordParams{
 		ID:       id,
 		Password: newHashedPassword,
 	})
@@ -229,54 +260,309 @@ func (r *Repository) ChangePassword(ctx context.Context, id, newHashedPassword s
 		return err
 	}
 	if n != 1 {
-		return ErrNotFound // хотя это не должно произойти
+		return ErrNotFound // пользователь удален
 	}
-	return nil
+	if err = writeAudit(ctx, qtx, models.AuditPasswordChange, id, nil, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) ChangeEmail(ctx context.Context, id, newEmail string) error {
-	n, err := r.q.ChangeEmail(ctx, db.ChangeEmailParams{
-		ID:    id,
-		Email: newEmail,
+func (r *Repository) GetPasswordHistory(ctx context.Context, id string, limit int) ([]string, error) {
+	return r.q.GetPasswordHistory(ctx, db.GetPasswordHistoryParams{
+		UserID: id,
+		Limit:  int32(limit),
+	})
+}
+
+func (r *Repository) SetPendingEmail(ctx context.Context, id, newEmail string) error {
+	n, err := r.q.SetPendingEmail(ctx, db.SetPendingEmailParams{
+		ID:           id,
+		PendingEmail: pgtype.Text{String: newEmail, Valid: true},
 	})
 	if err != nil {
 		return err
 	}
 	if n != 1 {
-		return ErrNotFound // хотя это не должно произойти
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *Repository) DeleteUser(ctx context.Context, id string) error {
-	n, err := r.q.DeleteUser(ctx, id)
+// ErrNotFound - ожидающей подтверждения почты нет или она уже другая,
+// ErrAlreadyExists - кто-то успел занять эту почту, пока письмо шло
+func (r *Repository) ConfirmEmailChange(ctx context.Context, id, newEmail string) error {
+	tx, err := r.p.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	oldEmail, err := qtx.GetEmailForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	n, err := qtx.ConfirmEmailChange(ctx, db.ConfirmEmailChangeParams{
+		ID:           id,
+		PendingEmail: pgtype.Text{String: newEmail, Valid: true},
+	})
+	if err != nil {
+		var errp *pgconn.PgError
+		if errors.As(err, &errp) {
+			if errp.Code == UniqueViolationCode {
+				return ErrAlreadyExists
+			}
+		}
+		return err
+	}
 	if n != 1 {
 		return ErrNotFound
 	}
-	return nil
+	err = writeAudit(ctx, qtx, models.AuditEmailChange, id, map[string]string{"email": maskEmail(oldEmail)}, map[string]string{"email": maskEmail(newEmail)})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// бан и снятие бана повторно ничего не меняют и в журнал не пишутся.
+// ErrNotFound - пользователя нет или он удален
+func (r *Repository) SetBanned(ctx context.Context, id string, banned bool) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	wasBanned, err := qtx.SetBanned(ctx, db.SetBannedParams{ID: id, Banned: banned})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if wasBanned == banned {
+		return nil
+	}
+	action := models.AuditUserUnbanned
+	if banned {
+		action = models.AuditUserBanned
+	}
+	if err = writeAudit(ctx, qtx, action, id, nil, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) DeleteModer(ctx context.Context, id string) error {
-	n, err := r.q.DeleteModer(ctx, id)
+func (r *Repository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	// удалить последнего core админа нельзя, иначе фоновое удаление
+	// будет бесконечно упираться в ErrLastCoreAdmin
+	held, err := qtx.GetUserRoles(ctx, id)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(held, models.RoleCoreAdmin) {
+		if err = checkNotLastCoreAdmin(ctx, qtx); err != nil {
+			return err
+		}
+	}
+	n, err := qtx.ScheduleDeletion(ctx, db.ScheduleDeletionParams{
+		ID:                  id,
+		DeletionScheduledAt: pgtype.Timestamptz{Time: at, Valid: true},
+	})
 	if err != nil {
 		return err
 	}
 	if n != 1 {
 		return ErrNotFound
 	}
-	return nil
+	err = writeAudit(ctx, qtx, models.AuditDeletionScheduled, id, nil, map[string]time.Time{"deletion_scheduled_at": at})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) DeleteAdmin(ctx context.Context, id string) error {
-	n, err := r.q.DeleteAdmin(ctx, id)
+// возвращает true, если удаление было запланировано и теперь отменено
+func (r *Repository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	n, err := qtx.CancelDeletion(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if n != 1 {
+		return false, nil
+	}
+	if err = writeAudit(ctx, qtx, models.AuditDeletionCanceled, id, nil, nil); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *Repository) GetDueDeletions(ctx context.Context, limit int) ([]string, error) {
+	return r.q.GetDueDeletions(ctx, int32(limit))
+}
+
+// персональные данные затираются, права и сессии удаляются в той же транзакции.
+// почта удаляется и из очереди писем, и из счетчиков неудачных входов, а токены
+// из уже отправленных писем - чтобы по ним нельзя было вернуть доступ к записи
+func (r *Repository) AnonymizeUser(ctx context.Context, id string) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	// до блокировки строки пользователя, см. lockCoreAdmins
+	cores, err := lockCoreAdmins(ctx, qtx)
+	if err != nil {
+		return err
+	}
+	u, err := qtx.GetUserEmailsForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	emails := []string{u.Email}
+	if u.PendingEmail.Valid {
+		emails = append(emails, u.PendingEmail.String)
+	}
+	if err = qtx.DeleteUserMails(ctx, emails); err != nil {
+		return err
+	}
+	if err = qtx.DeleteUserLoginFailures(ctx, emails); err != nil {
+		return err
+	}
+	if err = qtx.DeleteUserMailTokens(ctx, id); err != nil {
+		return err
+	}
+	n, err := qtx.AnonymizeUser(ctx, id)
 	if err != nil {
 		return err
 	}
 	if n != 1 {
 		return ErrNotFound
 	}
+	roles, err := revokeAllRoles(ctx, qtx, id, cores)
+	if err != nil {
+		return err
+	}
+	if err = qtx.DeletePasswordHistory(ctx, id); err != nil {
+		return err
+	}
+	if _, err = qtx.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return err
+	}
+	// персональные данные в журнал не попадают, иначе анонимизация теряет смысл
+	if err = writeAudit(ctx, qtx, models.AuditAccountDeleted, id, map[string][]string{"roles": roles}, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, hash, userID, familyID string, expiresAt time.Time) error {
+	err := r.q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		TokenHash: hash,
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		var errp *pgconn.PgError
+		if errors.As(err, &errp) {
+			switch errp.Code {
+			case ForeignKeyViolationCode:
+				return ErrNotFound
+			case UniqueViolationCode:
+				return ErrAlreadyExists
+			}
+		}
+		return err
+	}
 	return nil
 }
+
+func (r *Repository) GetRefreshToken(ctx context.Context, hash string) (models.RefreshToken, error) {
+	t, err := r.q.GetRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.RefreshToken{}, ErrNotFound
+		}
+		return models.RefreshToken{}, err
+	}
+	return models.RefreshToken{
+		Hash:      t.TokenHash,
+		UserID:    t.UserID,
+		FamilyID:  t.FamilyID,
+		ExpiresAt: t.ExpiresAt.Time,
+		IsUsed:    t.UsedAt.Valid,
+		IsRevoked: t.RevokedAt.Valid,
+	}, nil
+}
+
+// старый токен помечается использованным и в той же транзакции сохраняется новый,
+// ErrNotFound означает, что старый токен уже кто-то использовал или отозвал
+func (r *Repository) RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	n, err := qtx.UseRefreshToken(ctx, oldHash)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrNotFound
+	}
+
+	err = qtx.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		TokenHash: next.Hash,
+		UserID:    next.UserID,
+		FamilyID:  next.FamilyID,
+		ExpiresAt: pgtype.Timestamptz{Time: next.ExpiresAt, Valid: true},
+	})
+	if err != nil {
+		var errp *pgconn.PgError
+		if errors.As(err, &errp) {
+			if errp.Code == UniqueViolationCode {
+				return ErrAlreadyExists
+			}
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.q.RevokeRefreshTokenFamily(ctx, familyID)
+	return err
+}
+
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	_, err := r.q.RevokeUserRefreshTokens(ctx, userID)
+	return err
+}
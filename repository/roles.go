@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/glekoz/online-shop_user/repository/db"
+	"github.com/glekoz/online-shop_user/shared/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	roleActionGrant  = "grant"
+	roleActionRevoke = "revoke"
+)
+
+func (r *Repository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return r.q.GetUserRoles(ctx, userID)
+}
+
+func (r *Repository) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return r.q.HasPermission(ctx, db.HasPermissionParams{
+		UserID:     userID,
+		Permission: permission,
+	})
+}
+
+// вместе с ролью выдаются все младшие роли, которых у пользователя еще нет.
+// ErrAlreadyExists - роль уже есть, ErrNotFound - пользователя нет или он удален
+func (r *Repository) GrantRole(ctx context.Context, userID, role, actorID string) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	held, err := lockUserRoles(ctx, qtx, userID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(held, role) {
+		return ErrAlreadyExists
+	}
+	for _, rl := range append(models.LowerRoles(role), role) {
+		if slices.Contains(held, rl) {
+			continue
+		}
+		err = qtx.GrantRole(ctx, db.GrantRoleParams{
+			UserID:    userID,
+			RoleID:    rl,
+			GrantedBy: nullableText(actorID),
+		})
+		if err != nil {
+			return err
+		}
+		if err = addRoleAssignment(ctx, qtx, userID, rl, roleActionGrant, actorID); err != nil {
+			return err
+		}
+	}
+	after, err := qtx.GetUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = writeAudit(ctx, qtx, models.AuditRoleGrant, userID, map[string][]string{"roles": held}, map[string][]string{"roles": after})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// младшую роль нельзя снять, пока есть старшая, а последнего core админа
+// разжаловать нельзя, иначе некому будет управлять админами
+func (r *Repository) RevokeRole(ctx context.Context, userID, role, actorID string) error {
+	tx, err := r.p.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.q.WithTx(tx)
+
+	var cores []string
+	if role == models.RoleCoreAdmin {
+		if cores, err = lockCoreAdmins(ctx, qtx); err != nil {
+			return err
+		}
+	}
+	held, err := lockUserRoles(ctx, qtx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(held, role) {
+		return ErrNotFound
+	}
+	for _, higher := range models.HigherRoles(role) {
+		if slices.Contains(held, higher) {
+			return ErrHasHigherRole
+		}
+	}
+	if role == models.RoleCoreAdmin && len(cores) <= 1 {
+		return ErrLastCoreAdmin
+	}
+	if _, err = qtx.RevokeRole(ctx, db.RevokeRoleParams{UserID: userID, RoleID: role}); err != nil {
+		return err
+	}
+	if err = addRoleAssignment(ctx, qtx, userID, role, roleActionRevoke, actorID); err != nil {
+		return err
+	}
+	after := slices.DeleteFunc(slices.Clone(held), func(r string) bool { return r == role })
+	err = writeAudit(ctx, qtx, models.AuditRoleRevoke, userID, map[string][]string{"roles": held}, map[string][]string{"roles": after})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// вызывается в транзакции удаления пользователя, роли снимает система.
+// cores - обладатели core_admin, заблокированные в начале транзакции.
+// возвращает снятые роли
+func revokeAllRoles(ctx context.Context, qtx *db.Queries, userID string, cores []string) ([]string, error) {
+	held, err := qtx.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(held, models.RoleCoreAdmin) && len(cores) <= 1 {
+		return nil, ErrLastCoreAdmin
+	}
+	revoked, err := qtx.RevokeAllRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range revoked {
+		if err = addRoleAssignment(ctx, qtx, userID, role, roleActionRevoke, ""); err != nil {
+			return nil, err
+		}
+	}
+	return revoked, nil
+}
+
+func lockUserRoles(ctx context.Context, qtx *db.Queries, userID string) ([]string, error) {
+	_, err := qtx.LockUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return qtx.GetUserRoles(ctx, userID)
+}
+
+// транзакции, которым нужно число core админов, блокируют их строки первыми,
+// до строки пользователя. иначе разжалование двух core админов друг другом
+// блокирует сначала каждый свою строку пользователя, а потом ждет чужую
+func lockCoreAdmins(ctx context.Context, qtx *db.Queries) ([]string, error) {
+	return qtx.GetRoleHoldersForUpdate(ctx, models.RoleCoreAdmin)
+}
+
+func checkNotLastCoreAdmin(ctx context.Context, qtx *db.Queries) error {
+	cores, err := lockCoreAdmins(ctx, qtx)
+	if err != nil {
+		return err
+	}
+	if len(cores) <= 1 {
+		return ErrLastCoreAdmin
+	}
+	return nil
+}
+
+func addRoleAssignment(ctx context.Context, qtx *db.Queries, userID, role, action, actorID string) error {
+	return qtx.AddRoleAssignment(ctx, db.AddRoleAssignmentParams{
+		UserID:  userID,
+		RoleID:  role,
+		Action:  action,
+		ActorID: nullableText(actorID),
+	})
+}
@@ -14,6 +14,11 @@ func (e *ErrorLogData) Error() string {
 	return e.Err.Error()
 }
 
+// без этого errors.Is не видит исходную ошибку, и обработчик отдает Internal
+func (e *ErrorLogData) Unwrap() error {
+	return e.Err
+}
+
 // WrapError нужен, когда вместе с ошибкой нужно передать дополнительные поля
 // если никакой дополнительной информации нет, то и оборачивать нечем
 func WrapError(ctx context.Context, err error) error {
@@ -17,8 +17,11 @@ type MyJSONLogHandler struct {
 
 type LogData struct {
 	UserID    string
+	Service   string // вызывающий сервис, если запрос не от пользователя
 	IPAddress string
 	Method    string
+	TraceID   string // текущий спан, чтобы по записи в логе найти трассу
+	SpanID    string
 	Details   map[string]any
 }
 
@@ -35,12 +38,18 @@ func (h *MyJSONLogHandler) Handle(ctx context.Context, rec slog.Record) error {
 		if ld.UserID != "" {
 			rec.Add("user_id", ld.UserID)
 		}
+		if ld.Service != "" {
+			rec.Add("service", ld.Service)
+		}
 		if ld.IPAddress != "" {
 			rec.Add("ip", ld.IPAddress)
 		}
 		if ld.Method != "" {
 			rec.Add("method", ld.Method)
 		}
+		if ld.TraceID != "" {
+			rec.Add("trace_id", ld.TraceID, "span_id", ld.SpanID)
+		}
 		if ld.Details != nil {
 			rec.Add("details", ld.Details)
 		}
@@ -64,6 +73,14 @@ func WithUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, LogDataKey, LogData{UserID: userID})
 }
 
+func WithService(ctx context.Context, service string) context.Context {
+	if ld, ok := ctx.Value(LogDataKey).(LogData); ok {
+		ld.Service = service
+		return context.WithValue(ctx, LogDataKey, ld)
+	}
+	return context.WithValue(ctx, LogDataKey, LogData{Service: service})
+}
+
 func WithIPAddress(ctx context.Context, ipAddress string) context.Context {
 	if ld, ok := ctx.Value(LogDataKey).(LogData); ok {
 		ld.IPAddress = ipAddress
@@ -80,6 +97,14 @@ func WithMethod(ctx context.Context, method string) context.Context {
 	return context.WithValue(ctx, LogDataKey, LogData{Method: method})
 }
 
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	if ld, ok := ctx.Value(LogDataKey).(LogData); ok {
+		ld.TraceID, ld.SpanID = traceID, spanID
+		return context.WithValue(ctx, LogDataKey, ld)
+	}
+	return context.WithValue(ctx, LogDataKey, LogData{TraceID: traceID, SpanID: spanID})
+}
+
 // это в основном для ошибок
 func WithDetails(ctx context.Context, key string, detail any) context.Context {
 	if ld, ok := ctx.Value(LogDataKey).(LogData); ok {
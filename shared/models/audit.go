@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// действия, которые попадают в журнал аудита
+const (
+	AuditLogin             = "login"
+	AuditLoginFailed       = "login.failed"
+	AuditAccountLocked     = "account.locked"
+	AuditAccountUnlocked   = "account.unlocked"
+	AuditPasswordChange    = "password.change"
+	AuditEmailChange       = "email.change"
+	AuditRoleGrant         = "role.grant"
+	AuditRoleRevoke        = "role.revoke"
+	AuditDeletionScheduled = "account.deletion_scheduled"
+	AuditDeletionCanceled  = "account.deletion_canceled"
+	AuditAccountDeleted    = "account.deleted"
+	AuditUserBanned        = "user.banned"
+	AuditUserUnbanned      = "user.unbanned"
+)
+
+var AuditActions = []string{
+	AuditLogin, AuditLoginFailed, AuditAccountLocked, AuditAccountUnlocked, AuditPasswordChange, AuditEmailChange,
+	AuditRoleGrant, AuditRoleRevoke,
+	AuditDeletionScheduled, AuditDeletionCanceled, AuditAccountDeleted,
+	AuditUserBanned, AuditUserUnbanned,
+}
+
+const MaxAuditPageSize = 200
+
+type AuditEntry struct {
+	ID        int64
+	ActorID   string // пусто, если действие выполнено системой
+	TargetID  string
+	Action    string
+	IP        string
+	Before    []byte // JSON, nil - нечего сохранять
+	After     []byte
+	CreatedAt time.Time
+}
+
+// пустые поля не фильтруют
+type AuditFilter struct {
+	TargetID string
+	ActorID  string
+	Action   string
+	From     time.Time
+	To       time.Time
+	BeforeID int64 // курсор: записи с id меньше этого
+	Limit    int
+}
@@ -1,21 +1,27 @@
 package models
 
+import "time"
+
 // то, что используется при входе в аккаунт и хранится в токене
 type UserTokenWithPassword struct {
 	ID             string
 	Name           string
 	HashedPassword string `json:"-"`
-	IsModer        bool
-	IsAdmin        bool
-	IsCore         bool
+	// вход в аккаунт отменяет запланированное удаление
+	IsDeletionScheduled bool
+	Permissions         []string
+	Locale              string // нужен для письма о блокировке аккаунта
 }
 
 type UserToken struct {
-	ID      string
-	Name    string
-	IsModer bool
-	IsAdmin bool
-	IsCore  bool
+	ID          string
+	Name        string
+	Permissions []string // права всех ролей пользователя, см. roles.go
+
+	// заполняются только при разборе токена
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
 
 // то, что видно пользователю на его странице профиля
@@ -24,9 +30,41 @@ type User struct {
 	Name             string
 	Email            string
 	IsEmailConfirmed bool
-	// день рождения
-	// адрес
-	// телефон
+	PendingEmail     string    // новая почта, ожидающая подтверждения
+	Birthday         time.Time // нулевое значение - не указан
+	Phone            string
+	ShippingAddress  Address // адрес доставки по умолчанию
+	Locale           string  // язык писем
+}
+
+// языки писем, у новых пользователей без выбранного языка - DefaultLocale
+const (
+	LocaleRU      = "ru"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleRU
+)
+
+var Locales = []string{LocaleRU, LocaleEN}
+
+type Address struct {
+	Country    string
+	City       string
+	Street     string
+	PostalCode string
+}
+
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// частичное обновление профиля: nil - поле не меняется,
+// указатель на нулевое значение - поле очищается
+type ProfileUpdate struct {
+	Name            *string
+	Birthday        *time.Time
+	Phone           *string
+	ShippingAddress *Address
+	Locale          *string
 }
 
 // то, что видно администратору в интерфейсе управления пользователями
@@ -35,12 +73,86 @@ type UserInfo struct {
 	Name             string
 	Email            string
 	IsEmailConfirmed bool
-	IsModer          bool
-	IsAdmin          bool
-	IsCore           bool
+	Roles            []string
+}
+
+// набор прав пользователя для других сервисов магазина
+type Permissions struct {
+	UserID           string
+	Found            bool
+	IsEmailConfirmed bool
+	IsBanned         bool
+	Roles            []string
+	Permissions      []string
+}
+
+// счетчик неудачных попыток входа, IP пустой - счетчик для почты с любых адресов
+type LoginFailures struct {
+	Email        string
+	IP           string
+	Failures     int
+	BlockedUntil time.Time
+	IsLocked     bool
+}
+
+// правила счетчика попыток входа. после FreeAttempts попыток каждая следующая
+// удваивает паузу, начиная с BackoffBase, а попытка номер LockThreshold
+// блокирует аккаунт на LockDuration (LockThreshold = 0 - без блокировки)
+type LoginPolicy struct {
+	Window        time.Duration // попытки старше этого забываются
+	FreeAttempts  int
+	BackoffBase   time.Duration
+	BackoffMax    time.Duration
+	LockThreshold int
+	LockDuration  time.Duration
+}
+
+// засчитанная попытка входа, Locked - эта попытка заблокировала аккаунт
+type LoginAttempt struct {
+	Failures int
+	Locked   bool
+}
+
+// токен из письма, хранится только хэш. Key - назначение токена и его владелец
+type MailToken struct {
+	Key       string
+	UserID    string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+// письмо в очереди на отправку, Data - параметры шаблона в JSON
+type OutboxMail struct {
+	ID       int64
+	Template string
+	Locale   string
+	To       string
+	Data     []byte
+	Attempts int // с учетом текущей
+}
+
+// состояние очереди писем для мониторинга
+type OutboxStats struct {
+	Pending       int           // ждут отправки, включая повторные
+	Retrying      int           // хотя бы одна попытка не удалась
+	Dead          int           // исчерпали попытки
+	OldestPending time.Duration // сколько ждет самое старое неотправленное письмо
+}
+
+// рефреш токен хранится в БД только в виде хэша
+type RefreshToken struct {
+	Hash      string
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+	IsUsed    bool
+	IsRevoked bool
 }
 
-type Admin struct {
-	ID     string
-	IsCore bool
+// публичный ключ для проверки токенов другими сервисами
+type JWK struct {
+	ID   string // kid
+	N    string
+	E    string
+	PKIX []byte // DER в base64
 }
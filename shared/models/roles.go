@@ -0,0 +1,45 @@
+package models
+
+import "slices"
+
+// Роли выдаются пользователям, а права проверяются по строкам вида "объект:действие".
+// Какие права дает роль, хранится в БД (role_permissions), здесь только имена,
+// на которые ссылается код.
+const (
+	RoleModer     = "moder"      // чистит комментарии
+	RoleAdmin     = "admin"      // следит за магазином и назначает модераторов
+	RoleCoreAdmin = "core_admin" // назначает и разжалует админов
+)
+
+const (
+	PermCommentsDelete   = "comments:delete"
+	PermUsersRead        = "users:read" // чужие профили и поиск пользователей
+	PermUsersDelete      = "users:delete"
+	PermUsersBan         = "users:ban" // бан виден другим сервисам через CheckPermissions
+	PermModersManage     = "moders:manage"
+	PermAdminsManage     = "admins:manage"
+	PermCoreAdminsManage = "core_admins:manage"
+	PermAuditRead        = "audit:read"
+)
+
+// роли упорядочены по старшинству: старшая роль выдается вместе со всеми младшими,
+// а младшую нельзя снять, пока у пользователя есть старшая
+var roleHierarchy = []string{RoleModer, RoleAdmin, RoleCoreAdmin}
+
+// младшие роли, которые выдаются вместе с role
+func LowerRoles(role string) []string {
+	i := slices.Index(roleHierarchy, role)
+	if i < 0 {
+		return nil
+	}
+	return roleHierarchy[:i:i]
+}
+
+// старшие роли, которые надо снять перед снятием role
+func HigherRoles(role string) []string {
+	i := slices.Index(roleHierarchy, role)
+	if i < 0 {
+		return nil
+	}
+	return roleHierarchy[i+1:]
+}
@@ -1,11 +1,18 @@
 package models
 
-import "github.com/glekoz/online-shop_user/shared/validator"
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glekoz/online-shop_user/shared/validator"
+)
 
 type RegisterUserReq struct {
 	Username string
 	Password string
 	Email    string
+	Locale   string // пустой - DefaultLocale
 }
 
 func (r *RegisterUserReq) Validate(v *validator.Validator) {
@@ -21,6 +28,10 @@ func (r *RegisterUserReq) Validate(v *validator.Validator) {
 	v.Check(r.Email != "", "email", "must be provided")
 	v.Check(len(r.Email) <= 100, "email", "must not be more than 100 characters long")
 	v.Check(validator.Matches(r.Email, validator.EmailRX), "email", "must be a valid email address")
+
+	if r.Locale != "" {
+		v.Check(validator.In(r.Locale, Locales...), "locale", "must be one of: "+strings.Join(Locales, ", "))
+	}
 }
 
 type LoginUserReq struct {
@@ -38,3 +49,178 @@ func (r *LoginUserReq) Validate(v *validator.Validator) {
 	v.Check(len(r.Password) <= 100, "password", "must not be more than 100 characters long")
 
 }
+
+type ResetPasswordReq struct {
+	UserID      string
+	ResetToken  string
+	NewPassword string
+}
+
+func (r *ResetPasswordReq) Validate(v *validator.Validator) {
+	v.Check(r.UserID != "", "user id", "must be provided")
+	v.Check(r.ResetToken != "", "reset token", "must be provided")
+
+	v.Check(r.NewPassword != "", "new password", "must be provided")
+	v.Check(len(r.NewPassword) >= 6, "new password", "must be at least 6 characters long")
+	v.Check(len(r.NewPassword) <= 100, "new password", "must not be more than 100 characters long")
+	v.Check(validator.ValidPassword(r.NewPassword), "new password", "must contain at least one uppercase letter, one lowercase letter, one digit, and one special character")
+}
+
+type ChangePasswordReq struct {
+	CurrentPassword string
+	NewPassword     string
+}
+
+func (r *ChangePasswordReq) Validate(v *validator.Validator) {
+	v.Check(r.CurrentPassword != "", "current password", "must be provided")
+
+	v.Check(r.NewPassword != "", "new password", "must be provided")
+	v.Check(len(r.NewPassword) >= 6, "new password", "must be at least 6 characters long")
+	v.Check(len(r.NewPassword) <= 100, "new password", "must not be more than 100 characters long")
+	v.Check(validator.ValidPassword(r.NewPassword), "new password", "must contain at least one uppercase letter, one lowercase letter, one digit, and one special character")
+	v.Check(r.NewPassword != r.CurrentPassword, "new password", "must differ from the current password")
+}
+
+// поиск пользователей админом по началу почты, поэтому адрес может быть неполным
+type EmailPrefixReq struct {
+	Email string
+}
+
+func (r *EmailPrefixReq) Validate(v *validator.Validator) {
+	v.Check(r.Email != "", "email", "must be provided")
+	v.Check(len(r.Email) <= 100, "email", "must not be more than 100 characters long")
+}
+
+const MaxUserIDsBatch = 100
+
+type UserIDsReq struct {
+	IDs []string
+}
+
+func (r *UserIDsReq) Validate(v *validator.Validator) {
+	v.Check(len(r.IDs) > 0, "ids", "must be provided")
+	v.Check(len(r.IDs) <= MaxUserIDsBatch, "ids", "must not be more than 100 ids")
+	v.Check(!slices.Contains(r.IDs, ""), "ids", "must not contain empty ids")
+}
+
+type AuditLogReq struct {
+	Filter AuditFilter
+	Cursor string
+}
+
+func (r *AuditLogReq) Validate(v *validator.Validator) {
+	f := r.Filter
+	v.Check(f.Action == "" || validator.In(f.Action, AuditActions...), "action", "must be a known audit action")
+	v.Check(f.From.IsZero() || f.To.IsZero() || f.From.Before(f.To), "from", "must be before to")
+	v.Check(f.Limit >= 0, "limit", "must not be negative")
+	v.Check(f.Limit <= MaxAuditPageSize, "limit", "must not be more than 200")
+	v.Check(len(r.Cursor) <= 100, "cursor", "must not be more than 100 characters long")
+}
+
+type EmailReq struct {
+	Email string
+}
+
+func (r *EmailReq) Validate(v *validator.Validator) {
+	v.Check(r.Email != "", "email", "must be provided")
+	v.Check(len(r.Email) <= 100, "email", "must not be more than 100 characters long")
+	v.Check(validator.Matches(r.Email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+// пути маски обновления профиля, совпадают с именами полей в user.proto
+const (
+	ProfileName            = "name"
+	ProfileBirthday        = "birthday"
+	ProfilePhone           = "phone"
+	ProfileShippingAddress = "shippingAddress"
+	ProfileLocale          = "locale"
+)
+
+const BirthdayLayout = time.DateOnly
+
+// обновляются только поля из Paths, пустое значение поля очищает его (кроме имени)
+type UpdateProfileReq struct {
+	Paths           []string
+	Name            string
+	Birthday        string
+	Phone           string
+	ShippingAddress Address
+	Locale          string
+}
+
+func (r *UpdateProfileReq) Validate(v *validator.Validator) {
+	v.Check(len(r.Paths) > 0, "update mask", "must contain at least one field")
+	v.Check(validator.Unique(r.Paths), "update mask", "must not contain duplicate fields")
+	for _, p := range r.Paths {
+		switch p {
+		case ProfileName:
+			v.Check(r.Name != "", "name", "must be provided")
+			v.Check(len(r.Name) >= 3, "name", "must be at least 3 characters long")
+			v.Check(len(r.Name) <= 50, "name", "must not be more than 50 characters long")
+		case ProfileBirthday:
+			if r.Birthday == "" {
+				continue
+			}
+			b, err := time.Parse(BirthdayLayout, r.Birthday)
+			if err != nil {
+				v.AddError("birthday", "must be a date in YYYY-MM-DD format")
+				continue
+			}
+			v.Check(b.Before(time.Now()), "birthday", "must be in the past")
+			v.Check(b.After(time.Now().AddDate(-150, 0, 0)), "birthday", "must be a real date of birth")
+		case ProfilePhone:
+			if r.Phone == "" {
+				continue
+			}
+			v.Check(validator.Matches(r.Phone, validator.PhoneRX), "phone", "must be in international format, e.g. +79991234567")
+		case ProfileShippingAddress:
+			a := r.ShippingAddress
+			if a.IsZero() {
+				continue
+			}
+			v.Check(a.Country != "", "shipping address country", "must be provided")
+			v.Check(len(a.Country) <= 60, "shipping address country", "must not be more than 60 characters long")
+			v.Check(a.City != "", "shipping address city", "must be provided")
+			v.Check(len(a.City) <= 100, "shipping address city", "must not be more than 100 characters long")
+			v.Check(a.Street != "", "shipping address street", "must be provided")
+			v.Check(len(a.Street) <= 200, "shipping address street", "must not be more than 200 characters long")
+			v.Check(a.PostalCode != "", "shipping address postal code", "must be provided")
+			v.Check(len(a.PostalCode) <= 20, "shipping address postal code", "must not be more than 20 characters long")
+		case ProfileLocale:
+			if r.Locale == "" {
+				continue
+			}
+			v.Check(validator.In(r.Locale, Locales...), "locale", "must be one of: "+strings.Join(Locales, ", "))
+		default:
+			v.AddError("update mask", "unknown field "+p)
+		}
+	}
+}
+
+// вызывать только после успешной валидации
+func (r *UpdateProfileReq) ProfileUpdate() ProfileUpdate {
+	var upd ProfileUpdate
+	for _, p := range r.Paths {
+		switch p {
+		case ProfileName:
+			upd.Name = &r.Name
+		case ProfileBirthday:
+			var b time.Time
+			if r.Birthday != "" {
+				b, _ = time.Parse(BirthdayLayout, r.Birthday)
+			}
+			upd.Birthday = &b
+		case ProfilePhone:
+			upd.Phone = &r.Phone
+		case ProfileShippingAddress:
+			upd.ShippingAddress = &r.ShippingAddress
+		case ProfileLocale:
+			locale := r.Locale
+			if locale == "" {
+				locale = DefaultLocale
+			}
+			upd.Locale = &locale
+		}
+	}
+	return upd
+}
@@ -7,6 +7,8 @@ import (
 )
 
 var (
+	// телефон в формате E.164: +79991234567
+	PhoneRX = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/glekoz/online-shop_user/config"
+	"github.com/glekoz/online-shop_user/shared/logger"
+)
+
+const (
+	ExporterNone   = "none"   // спаны не записываются, но trace id из запроса попадает в логи
+	ExporterStdout = "stdout" // для разработки, пишет в stderr, чтобы не смешиваться с логами
+	ExporterOTLP   = "otlp"
+)
+
+// глобальный tracer делегирует провайдеру, установленному позже в Setup (в тестах - tracingtest.InMemory)
+var tracer = otel.Tracer("github.com/glekoz/online-shop_user")
+
+// Setup устанавливает глобальный провайдер и W3C propagator.
+// возвращаемая функция отправляет оставшиеся спаны, ее нужно вызвать при остановке
+func Setup(ctx context.Context, cfg config.Tracing) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		// решение вызывающего сервиса о записи трассы сохраняется
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start начинает дочерний спан и кладет его id в logger.LogData,
+// чтобы записи в логе внутри спана можно было найти в трассе
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, name, opts...)
+	return withLogIDs(ctx, span), span
+}
+
+// WithSpan делает span текущим, не трогая остальные значения ctx
+func WithSpan(ctx context.Context, span trace.Span) context.Context {
+	return withLogIDs(trace.ContextWithSpan(ctx, span), span)
+}
+
+// End завершает спан, отмечая его ошибкой, если err не nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func withLogIDs(ctx context.Context, span trace.Span) context.Context {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return ctx
+	}
+	return logger.WithTrace(ctx, sc.TraceID().String(), sc.SpanID().String())
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/glekoz/online-shop_user/shared/logger"
+	"github.com/glekoz/online-shop_user/tracing/tracingtest"
+)
+
+func TestStartLogIDs(t *testing.T) {
+	tracingtest.InMemory()
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+
+	sc := span.SpanContext()
+	ld, ok := ctx.Value(logger.LogDataKey).(logger.LogData)
+	if !ok {
+		t.Fatal("no LogData in ctx")
+	}
+	if ld.TraceID != sc.TraceID().String() || ld.SpanID != sc.SpanID().String() {
+		t.Errorf("LogData ids %s/%s, span %s/%s", ld.TraceID, ld.SpanID, sc.TraceID(), sc.SpanID())
+	}
+
+	// дочерний спан заменяет span id, trace id остается
+	cctx, child := Start(logger.WithUserID(ctx, "u1"), "child")
+	defer child.End()
+	cld := cctx.Value(logger.LogDataKey).(logger.LogData)
+	if cld.TraceID != ld.TraceID || cld.SpanID != child.SpanContext().SpanID().String() {
+		t.Errorf("child LogData ids %s/%s", cld.TraceID, cld.SpanID)
+	}
+	if cld.UserID != "u1" {
+		t.Error("other LogData fields are lost")
+	}
+
+	var buf bytes.Buffer
+	logger.New(&buf, nil).InfoContext(cctx, "test")
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["trace_id"] != cld.TraceID || rec["span_id"] != cld.SpanID {
+		t.Errorf("log record ids %v/%v", rec["trace_id"], rec["span_id"])
+	}
+}
+
+func TestStartNoRecording(t *testing.T) {
+	tracingtest.InMemory()
+	ctx := WithSpan(context.Background(), trace.SpanFromContext(context.Background()))
+	if _, ok := ctx.Value(logger.LogDataKey).(logger.LogData); ok {
+		t.Error("LogData added without a valid span")
+	}
+}
+
+func TestPgxTracer(t *testing.T) {
+	exp := tracingtest.InMemory()
+	var tr PgxTracer
+	query := func(ctx context.Context, sql string, err error) {
+		ctx = tr.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql})
+		tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1"), Err: err})
+	}
+
+	// вне трассы запросы не записываются
+	query(context.Background(), "SELECT 1", nil)
+	if n := len(exp.GetSpans()); n != 0 {
+		t.Fatalf("%d spans without a parent", n)
+	}
+
+	ctx, parent := Start(context.Background(), "request")
+	query(ctx, "-- name: GetUserByID :one\nSELECT * FROM users WHERE id = $1", pgx.ErrNoRows)
+	query(ctx, "begin", nil)
+	query(ctx, "-- name: UpdateUser :exec\nUPDATE users SET name = $2", errors.New("boom"))
+	parent.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 3 queries and the parent", len(spans))
+	}
+	want := []struct {
+		name  string
+		error bool
+	}{
+		{"db.GetUserByID", false}, // ErrNoRows - обычный результат, а не сбой
+		{"db.begin", false},
+		{"db.UpdateUser", true},
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.Name != w.name {
+			t.Errorf("span %d is %q, want %q", i, s.Name, w.name)
+		}
+		if s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", s.Name)
+		}
+		if got := s.Status.Code == codes.Error; got != w.error {
+			t.Errorf("%s error status = %t, want %t", s.Name, got, w.error)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"-- name: GetUserByID :one\nSELECT 1", "db.GetUserByID"},
+		{"  -- name: ClaimMails :many\nUPDATE mail_outbox", "db.ClaimMails"},
+		{"begin", "db.begin"},
+		{"COMMIT", "db.commit"},
+		{"SELECT 1", "db.select"},
+	}
+	for _, tt := range tests {
+		if got := queryName(tt.sql); got != tt.want {
+			t.Errorf("queryName(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package tracingtest - трассировка для тестов, в сервис не подключается
+package tracingtest
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	once sync.Once
+	exp  = tracetest.NewInMemoryExporter()
+)
+
+// InMemory устанавливает глобальный провайдер, который синхронно складывает
+// все спаны в память, чтобы проверять трассировку без коллектора.
+// спаны доступны через GetSpans после их завершения.
+// глобальный tracer привязывается только к первому провайдеру, поэтому провайдер
+// один на процесс, а каждый вызов очищает уже записанные спаны
+func InMemory() *tracetest.InMemoryExporter {
+	once.Do(func() {
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	})
+	exp.Reset()
+	return exp
+}